@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the configuration files looked up in the working directory,
+// in order of preference
+var FileNames = []string{
+	"monique.toml",
+	".monique.toml",
+	"monique.yaml",
+	".monique.yaml",
+	"monique.yml",
+	".monique.yml",
+}
+
+// A command to run, with the steps to run before it
+type Command struct {
+	Command string            `toml:"command" yaml:"command"` // the command line
+	Pre     []string          `toml:"pre" yaml:"pre"`         // commands that must succeed before it is (re)started
+	Env     map[string]string `toml:"env" yaml:"env"`         // environment variables added to the command's
+}
+
+// Colors of the user interface, as ANSI color numbers or hex codes
+type Theme struct {
+	Title           string `toml:"title" yaml:"title"`
+	TitleText       string `toml:"title-text" yaml:"title-text"`
+	Match           string `toml:"match" yaml:"match"`
+	MatchText       string `toml:"match-text" yaml:"match-text"`
+	ActiveMatch     string `toml:"active-match" yaml:"active-match"`
+	ActiveMatchText string `toml:"active-match-text" yaml:"active-match-text"`
+}
+
+// Content of a configuration file.
+// Empty values are left to the command line flags and their defaults.
+type Config struct {
+	Command     `yaml:",inline"`   // the main command
+	Commands    map[string]Command `toml:"commands" yaml:"commands"`         // named commands, run side by side
+	Watch       []string           `toml:"watch" yaml:"watch"`               // paths to watch
+	Exts        []string           `toml:"exts" yaml:"exts"`                 // extensions of the files to watch
+	Include     []string           `toml:"include" yaml:"include"`           // glob patterns of the files to watch
+	Ignore      []string           `toml:"ignore" yaml:"ignore"`             // glob patterns of the paths not to watch
+	NoIgnore    *bool              `toml:"no-ignore" yaml:"no-ignore"`       // disregard .gitignore and .ignore files
+	Poll        string             `toml:"poll" yaml:"poll"`                 // polling interval, like "500ms"
+	NoHash      *bool              `toml:"no-hash" yaml:"no-hash"`           // restart on every write, even of identical content
+	Delay       *int               `toml:"delay" yaml:"delay"`               // in milliseconds
+	Grace       string             `toml:"grace" yaml:"grace"`               // like "5s"
+	Restart     string             `toml:"restart" yaml:"restart"`           // never, on-failure or always
+	MaxRestarts *int               `toml:"max-restarts" yaml:"max-restarts"` // 0 for unlimited
+	Backoff     string             `toml:"backoff" yaml:"backoff"`           // like "500ms..30s"
+	Keys        map[string]string  `toml:"keys" yaml:"keys"`                 // action -> comma separated keys
+	Filters     []string           `toml:"filters" yaml:"filters"`           // saved filter patterns
+	Scrollback  *int               `toml:"scrollback" yaml:"scrollback"`     // maximum number of lines kept, 0 for unlimited
+	Structured  *bool              `toml:"structured" yaml:"structured"`     // display JSON and logfmt lines by their fields
+	Levels      map[string]string  `toml:"levels" yaml:"levels"`             // level name -> pattern of the lines of that level
+	ErrorFile   string             `toml:"errorfile" yaml:"errorfile"`       // where the source locations of each run are written
+	Keep        *bool              `toml:"keep" yaml:"keep"`                 // keep the output of every run, under a header
+	History     *int               `toml:"history" yaml:"history"`           // number of runs kept, 0 for unlimited
+	DiffIgnore  *string            `toml:"diff-ignore" yaml:"diff-ignore"`   // pattern of what is ignored when comparing runs
+	Control     string             `toml:"control" yaml:"control"`           // path of the control socket
+	HTTP        string             `toml:"http" yaml:"http"`                 // address of the web interface, like "127.0.0.1:7777"
+	Theme       Theme              `toml:"theme" yaml:"theme"`
+	Path        string             `toml:"-" yaml:"-"` // the file it was loaded from
+}
+
+// Returns the path of the first configuration file found in dir,
+// or an empty string if there is none
+func Find(dir string) string {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// Loads the configuration file at path, with the values of the named
+// profile, if any, taking precedence over the top-level ones
+func Load(path string, profile string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{Path: path}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = loadYAML(content, profile, config)
+	default:
+		err = loadTOML(content, profile, config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+func loadTOML(content []byte, profile string, config *Config) error {
+	var profiles struct {
+		Profiles map[string]toml.Primitive `toml:"profiles"`
+	}
+
+	metadata, err := toml.Decode(string(content), &profiles)
+	if err != nil {
+		return err
+	}
+	if _, err := toml.Decode(string(content), config); err != nil {
+		return err
+	}
+
+	if profile == "" {
+		return nil
+	}
+
+	values, ok := profiles.Profiles[profile]
+	if !ok {
+		return unknownProfile(profile, keys(profiles.Profiles))
+	}
+
+	// only the keys present in the profile are overwritten
+	return metadata.PrimitiveDecode(values, config)
+}
+
+func loadYAML(content []byte, profile string, config *Config) error {
+	var profiles struct {
+		Profiles map[string]yaml.Node `yaml:"profiles"`
+	}
+
+	if err := yaml.Unmarshal(content, &profiles); err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return err
+	}
+
+	if profile == "" {
+		return nil
+	}
+
+	values, ok := profiles.Profiles[profile]
+	if !ok {
+		return unknownProfile(profile, keys(profiles.Profiles))
+	}
+
+	// only the keys present in the profile are overwritten
+	return values.Decode(config)
+}
+
+// The names of the named commands, sorted
+func (c *Config) CommandNames() []string {
+	return keys(c.Commands)
+}
+
+func unknownProfile(profile string, known []string) error {
+	if len(known) == 0 {
+		return fmt.Errorf("unknown profile %q, no profiles are defined", profile)
+	}
+
+	return fmt.Errorf(
+		"unknown profile %q, expected one of %s",
+		profile,
+		strings.Join(known, ", "),
+	)
+}
+
+func keys[v any](m map[string]v) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,106 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Sends requests to the control socket of a running monique
+type Client struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+// Connects to the socket at path
+func Dial(path string) (*Client, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(conn)
+	// output events are as large as the output read at once
+	scanner.Buffer(make([]byte, 4096), maxRequestSize)
+
+	return &Client{conn: conn, scanner: scanner}, nil
+}
+
+// The sockets of the running monique processes, at their default path
+func Running() ([]string, error) {
+	paths, err := FindSockets()
+	if err != nil {
+		return nil, err
+	}
+
+	running := []string{}
+	for _, path := range paths {
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			running = append(running, path)
+		}
+	}
+
+	return running, nil
+}
+
+// Sends a request, and returns the response, whose error is returned
+// as an error when the request failed
+func (c *Client) Send(request Request) (Response, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return Response{}, err
+	}
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		return Response{}, err
+	}
+
+	response := Response{}
+	if err := c.read(&response); err != nil {
+		return Response{}, err
+	}
+	if !response.OK {
+		return response, errors.New(response.Error)
+	}
+
+	return response, nil
+}
+
+// Calls handle with each event received once tailing, until the
+// connection is closed
+func (c *Client) Events(handle func(Event)) error {
+	for {
+		event := Event{}
+		if err := c.read(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		handle(event)
+	}
+}
+
+// Reads the next message
+func (c *Client) read(message any) error {
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+
+	if err := json.Unmarshal(c.scanner.Bytes(), message); err != nil {
+		return fmt.Errorf("invalid message: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,138 @@
+// Package control lets editors and scripts drive a running monique through
+// a Unix domain socket, with JSON messages, one per line
+package control
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+// Commands of the requests
+const (
+	CommandRestart   = "restart"    // restarts the commands
+	CommandStop      = "stop"       // stops the commands
+	CommandSetFilter = "set-filter" // replaces the filter stack of a pane
+	CommandSetSearch = "set-search" // searches a pane
+	CommandStatus    = "status"     // tells how the commands are doing
+	CommandTail      = "tail"       // sends the events of the commands as they happen
+)
+
+// A request, like {"command":"set-filter","source":"api","pattern":"ERROR"}
+type Request struct {
+	Command string `json:"command"`
+	Source  string `json:"source,omitempty"`  // name of a command, every command, or the active pane, when empty
+	Pattern string `json:"pattern,omitempty"` // set-filter and set-search: the pattern, empty to clear
+}
+
+// The response to a request, like {"ok":false,"error":"unknown command \"lint\""}
+type Response struct {
+	OK      bool           `json:"ok"`
+	Error   string         `json:"error,omitempty"`
+	Sources []SourceStatus `json:"sources,omitempty"` // status: how each command is doing
+}
+
+// States of a command
+const (
+	StateIdle       = "idle"       // not started yet
+	StateRunning    = "running"    // running, or running its pre-steps
+	StateExited     = "exited"     // exited on its own
+	StateStopped    = "stopped"    // killed by monique
+	StateRestarting = "restarting" // exited, and restarting after a delay
+	StateFailed     = "failed"     // could not be started
+)
+
+// How a command is doing
+type SourceStatus struct {
+	Name    string               `json:"name"`
+	Command string               `json:"command"`
+	State   string               `json:"state"`
+	Runs    int                  `json:"runs"`              // number of times it started
+	Started *time.Time           `json:"started,omitempty"` // when the last run started
+	Exit    *mediator.ExitStatus `json:"exit,omitempty"`    // how the last run ended, if it did
+	// why the last run, or its pre-steps, failed, if they did
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Types of events
+const (
+	EventStart   = "start"
+	EventStep    = "step"
+	EventOutput  = "output"
+	EventExit    = "exit"
+	EventKill    = "kill"
+	EventError   = "error"
+	EventRestart = "restart" // an automatic restart was scheduled
+)
+
+// What happened to a command, sent after the response to tail,
+// like {"source":"api","type":"output","output":"listening on :8080\r\n"}
+type Event struct {
+	Source  string                     `json:"source"`
+	Type    string                     `json:"type"`
+	Command string                     `json:"command,omitempty"` // start: the command line
+	Output  string                     `json:"output,omitempty"`  // output: the bytes received
+	Error   string                     `json:"error,omitempty"`   // error: why the command could not start
+	Status  *mediator.ExitStatus       `json:"status,omitempty"`  // exit: how the run ended
+	Step    *mediator.Step             `json:"step,omitempty"`    // step: the step starting
+	Restart *mediator.ScheduledRestart `json:"restart,omitempty"` // restart: when the command restarts
+}
+
+// Directory of the sockets: $XDG_RUNTIME_DIR, or a directory of the
+// user in the temporary directory, like /tmp/monique-1000
+func socketDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+
+	return filepath.Join(os.TempDir(), fmt.Sprintf("monique-%d", os.Getuid()))
+}
+
+// Checks that dir is a directory only the user can use, so that others
+// can neither reach nor plant sockets in it
+func checkPrivate(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !info.IsDir() || info.Mode().Perm()&0o077 != 0 || (ok && int(stat.Uid) != os.Getuid()) {
+		return fmt.Errorf("%s is not a directory only accessible to the user", dir)
+	}
+
+	return nil
+}
+
+// Path of the socket of this process, like /run/user/1000/monique-4242.sock.
+// The directory is created when missing
+func DefaultPath() (string, error) {
+	dir := socketDir()
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, fs.ErrExist) {
+		return "", err
+	}
+	if err := checkPrivate(dir); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("monique-%d.sock", os.Getpid())), nil
+}
+
+// Paths of the sockets of the other monique processes, at their default path
+func FindSockets() ([]string, error) {
+	dir := socketDir()
+	if err := checkPrivate(dir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return filepath.Glob(filepath.Join(dir, "monique-*.sock"))
+}
@@ -0,0 +1,509 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+// Maximum size of a request
+const maxRequestSize = 1024 * 1024
+
+// Filtering and searching, done by the user interface
+type View interface {
+	SetFilter(name string, pattern string)
+	SetSearch(name string, pattern string)
+}
+
+// A command the server controls
+type Source struct {
+	Name     string
+	Command  string
+	Mediator mediator.Mediator
+}
+
+// Answers the requests sent to the control socket
+type Server struct {
+	path     string
+	listener net.Listener
+	view     View // nil without the user interface
+	sources  []*source
+	mu       sync.Mutex
+	conns    map[*conn]bool // open connections
+	tails    map[*conn]bool // connections the events are sent to
+}
+
+// Listens on the socket at path, which is replaced if no other process
+// listens on it. view may be nil, filters and searches being refused then
+func NewServer(path string, view View, sources ...Source) (*Server, error) {
+	if err := removeStale(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := listenPrivate(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		path:     path,
+		listener: listener,
+		view:     view,
+		conns:    make(map[*conn]bool),
+		tails:    make(map[*conn]bool),
+	}
+	for _, src := range sources {
+		state := &source{
+			server:   s,
+			name:     src.Name,
+			command:  src.Command,
+			mediator: src.Mediator,
+			state:    StateIdle,
+		}
+		src.Mediator.AddListener(state)
+		s.sources = append(s.sources, state)
+	}
+
+	return s, nil
+}
+
+// Listens on a socket that only the user can use. It is created in a
+// directory of its own, only accessible to the user, then moved to path,
+// so that it is never reachable by others, even for a moment
+func listenPrivate(path string) (*net.UnixListener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".monique-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	created := filepath.Join(dir, "sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: created, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// the socket is removed from path when closed, by the server
+	listener.SetUnlinkOnClose(false)
+
+	if err := os.Chmod(created, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	if err := os.Rename(created, path); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// Removes the socket left at path by a process that is gone
+func removeStale(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return fmt.Errorf("%s is used by another monique", path)
+	}
+
+	return os.Remove(path)
+}
+
+// Path of the socket
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Accepts connections, until the server is closed
+func (s *Server) Serve() {
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Println("control socket:", err)
+			}
+			return
+		}
+
+		go s.handle(newConn(c))
+	}
+}
+
+// Stops listening, closes the connections and removes the socket
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	os.Remove(s.path)
+
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	return err
+}
+
+// Answers the requests of a connection, one per line, until it is closed
+func (s *Server) handle(c *conn) {
+	s.mu.Lock()
+	s.conns[c] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		delete(s.tails, c)
+		s.mu.Unlock()
+		c.end()
+	}()
+
+	scanner := bufio.NewScanner(c)
+	scanner.Buffer(make([]byte, 4096), maxRequestSize)
+	for scanner.Scan() {
+		request := Request{}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			c.send(Response{Error: fmt.Sprintf("invalid request: %s", err)})
+			continue
+		}
+
+		response := s.answer(request)
+		c.send(response)
+
+		// the events follow the response
+		if request.Command == CommandTail && response.OK {
+			c.setTail(request.Source)
+			s.mu.Lock()
+			s.tails[c] = true
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Server) answer(request Request) Response {
+	sources, err := s.selectSources(request.Source)
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+
+	switch request.Command {
+	case CommandRestart:
+		for _, src := range sources {
+			src.mediator.SendRequestRestart()
+		}
+
+	case CommandStop:
+		for _, src := range sources {
+			src.mediator.SendRequestStop()
+		}
+
+	case CommandSetFilter, CommandSetSearch:
+		if s.view == nil {
+			return Response{Error: fmt.Sprintf("%s requires the user interface", request.Command)}
+		}
+		if request.Command == CommandSetFilter {
+			s.view.SetFilter(request.Source, request.Pattern)
+		} else {
+			s.view.SetSearch(request.Source, request.Pattern)
+		}
+
+	case CommandStatus:
+		statuses := []SourceStatus{}
+		for _, src := range sources {
+			statuses = append(statuses, src.status())
+		}
+		return Response{OK: true, Sources: statuses}
+
+	case CommandTail:
+
+	default:
+		return Response{Error: fmt.Sprintf("unknown request %q", request.Command)}
+	}
+
+	return Response{OK: true}
+}
+
+// The named source, or every source when name is empty
+func (s *Server) selectSources(name string) ([]*source, error) {
+	if name == "" {
+		return s.sources, nil
+	}
+
+	for _, src := range s.sources {
+		if src.name == name {
+			return []*source{src}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown command %q", name)
+}
+
+// Sends an event to the connections tailing its source
+func (s *Server) broadcast(event Event) {
+	s.mu.Lock()
+	tails := make([]*conn, 0, len(s.tails))
+	for c := range s.tails {
+		tails = append(tails, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range tails {
+		if c.tails(event.Source) {
+			c.send(event)
+		}
+	}
+}
+
+// MARK: - Connections
+
+// Time given to a client to read a message, before it is left behind
+const writeTimeout = 5 * time.Second
+
+// Number of messages queued for a client, before it is disconnected
+// for not keeping up
+const connBuffer = 4096
+
+// A connection, written to by the server and the commands at the same time.
+// Messages are queued, and written by a goroutine of their own, so that a
+// client that does not read does not hold the commands up
+type conn struct {
+	net.Conn
+	mu       sync.Mutex
+	tailed   *string     // the name of the command it tails, empty for every command, nil until it tails
+	messages chan []byte // messages waiting to be written
+	done     chan struct{}
+	finish   sync.Once
+}
+
+func newConn(c net.Conn) *conn {
+	conn := &conn{
+		Conn:     c,
+		messages: make(chan []byte, connBuffer),
+		done:     make(chan struct{}),
+	}
+	go conn.write()
+
+	return conn
+}
+
+// Queues a message, written on a line, disconnecting the client when
+// it can't keep up
+func (c *conn) send(message any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Println("control socket:", err)
+		return
+	}
+
+	select {
+	case c.messages <- append(data, '\n'):
+	default:
+		c.Close()
+	}
+}
+
+// Writes the queued messages, until the connection is closed
+func (c *conn) write() {
+	for {
+		select {
+		case data := <-c.messages:
+			if !c.writeMessage(data) {
+				return
+			}
+		case <-c.done:
+			// the responses already queued are still written
+			for {
+				select {
+				case data := <-c.messages:
+					if !c.writeMessage(data) {
+						return
+					}
+				default:
+					c.Close()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *conn) writeMessage(data []byte) bool {
+	c.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.Write(data); err != nil {
+		// the client is gone, or too slow to keep up
+		c.Close()
+		return false
+	}
+
+	return true
+}
+
+// Closes the connection once the messages queued are written
+func (c *conn) end() {
+	c.finish.Do(func() { close(c.done) })
+}
+
+func (c *conn) setTail(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tailed = &name
+}
+
+// Whether the events of the named source are sent to the connection
+func (c *conn) tails(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.tailed != nil && (*c.tailed == "" || *c.tailed == name)
+}
+
+// MARK: - Sources
+
+// Keeps how a command is doing, and forwards what happens to it to the
+// connections tailing it.
+// Implements mediator.MediatorListener
+type source struct {
+	server   *Server
+	name     string
+	mediator mediator.Mediator
+	mu       sync.Mutex
+	command  string
+	state    string
+	runs     int
+	started  time.Time
+	exit     *mediator.ExitStatus
+	lastErr  error
+
+	// how the previous run was doing, as it goes on when the pre-steps
+	// of the new one fail
+	previousState string
+	previousExit  *mediator.ExitStatus
+}
+
+func (src *source) status() SourceStatus {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	status := SourceStatus{
+		Name:    src.name,
+		Command: src.command,
+		State:   src.state,
+		Runs:    src.runs,
+		Exit:    src.exit,
+	}
+	if src.lastErr != nil {
+		status.LastError = src.lastErr.Error()
+	}
+	if src.runs > 0 {
+		started := src.started
+		status.Started = &started
+	}
+
+	return status
+}
+
+func (src *source) setState(state string) {
+	src.mu.Lock()
+	src.state = state
+	src.mu.Unlock()
+}
+
+func (src *source) OnStart(command string) {
+	src.mu.Lock()
+	src.command = command
+	src.previousState, src.previousExit = src.state, src.exit
+	src.state = StateRunning
+	src.runs++
+	src.started = time.Now()
+	src.exit = nil
+	src.lastErr = nil
+	src.mu.Unlock()
+
+	src.server.broadcast(Event{Source: src.name, Type: EventStart, Command: command})
+}
+
+func (src *source) OnError(err error) {
+	src.mu.Lock()
+	src.lastErr = err
+	// the command was not started again, and the previous run goes on
+	var stepErr *mediator.StepError
+	if errors.As(err, &stepErr) {
+		src.state, src.exit = src.previousState, src.previousExit
+	} else {
+		src.state = StateFailed
+	}
+	src.mu.Unlock()
+
+	src.server.broadcast(Event{Source: src.name, Type: EventError, Error: err.Error()})
+}
+
+func (src *source) OnKill() {
+	src.setState(StateStopped)
+	src.server.broadcast(Event{Source: src.name, Type: EventKill})
+}
+
+func (src *source) OnStop() {
+}
+
+func (src *source) OnExit(status mediator.ExitStatus) {
+	src.mu.Lock()
+	src.state = StateExited
+	src.exit = &status
+	src.mu.Unlock()
+
+	src.server.broadcast(Event{Source: src.name, Type: EventExit, Status: &status})
+}
+
+func (src *source) OnStep(step mediator.Step) {
+	// the last step is the command itself, started once the previous
+	// run was stopped
+	if step.Index == step.Total {
+		src.mu.Lock()
+		src.state = StateRunning
+		src.exit = nil
+		src.mu.Unlock()
+	}
+
+	src.server.broadcast(Event{Source: src.name, Type: EventStep, Step: &step})
+}
+
+func (src *source) OnOutput(output string) {
+	src.server.broadcast(Event{Source: src.name, Type: EventOutput, Output: output})
+}
+
+func (src *source) OnRequestRestart() {
+}
+
+func (src *source) OnRequestStop() {
+}
+
+func (src *source) OnFilesChanged(changes mediator.ChangeSet) {
+}
+
+func (src *source) OnRestartScheduled(restart mediator.ScheduledRestart) {
+	src.setState(StateRestarting)
+	src.server.broadcast(Event{Source: src.name, Type: EventRestart, Restart: &restart})
+}
+
+func (src *source) OnCancelRestart() {
+	src.setState(StateExited)
+}
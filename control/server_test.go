@@ -0,0 +1,70 @@
+package control
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+func TestFailingPreStepKeepsTheState(t *testing.T) {
+	src := &source{server: &Server{}, name: "api", state: StateIdle}
+
+	src.OnStart("go run .")
+	src.OnStep(mediator.Step{Index: 2, Total: 2, Command: "go run ."})
+	src.OnStart("go run .")
+	src.OnStep(mediator.Step{Index: 1, Total: 2, Command: "go build ."})
+	src.OnError(&mediator.StepError{
+		Step:   mediator.Step{Index: 1, Total: 2, Command: "go build ."},
+		Status: mediator.ExitStatus{Command: "go build .", Code: 1},
+	})
+
+	status := src.status()
+	if status.State != StateRunning {
+		t.Errorf("state %q, want %q", status.State, StateRunning)
+	}
+	if status.LastError == "" {
+		t.Errorf("the failure of the pre-step is not reported")
+	}
+
+	// the next run starts without the error
+	src.OnStart("go run .")
+	if status := src.status(); status.LastError != "" {
+		t.Errorf("last error %q once started again, want none", status.LastError)
+	}
+}
+
+func TestFailingPreStepAfterExit(t *testing.T) {
+	src := &source{server: &Server{}, name: "api", state: StateIdle}
+
+	src.OnStart("go run .")
+	src.OnExit(mediator.ExitStatus{Command: "go run .", Code: 2})
+	src.OnStart("go run .")
+	src.OnError(&mediator.StepError{
+		Step:   mediator.Step{Index: 1, Total: 2, Command: "go build ."},
+		Status: mediator.ExitStatus{Command: "go build .", Code: 1},
+	})
+
+	status := src.status()
+	if status.State != StateExited {
+		t.Errorf("state %q, want %q", status.State, StateExited)
+	}
+	if status.Exit == nil || status.Exit.Code != 2 {
+		t.Errorf("last exit %v, want the exit of the previous run", status.Exit)
+	}
+}
+
+func TestFailingStartFails(t *testing.T) {
+	src := &source{server: &Server{}, name: "api", state: StateIdle}
+
+	src.OnStart("missing")
+	src.OnError(errors.New("exec: \"missing\": executable file not found in $PATH"))
+
+	status := src.status()
+	if status.State != StateFailed {
+		t.Errorf("state %q, want %q", status.State, StateFailed)
+	}
+	if status.LastError == "" {
+		t.Errorf("the error is not reported")
+	}
+}
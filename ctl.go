@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/term"
+
+	"github.com/gaelph/monique/control"
+	"github.com/gaelph/monique/mediator"
+	"github.com/gaelph/monique/plain"
+)
+
+// Runs `monique ctl`, sending a request to a running monique.
+// Returns the exit code
+func runCtl(args []string) int {
+	flags := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	path := flags.String("control", "", "path of the control socket (default: that of the only monique running)")
+	source := flags.String("source", "", "name of the command the request is about (default: every command, or the active pane)")
+	flags.Usage = func() {
+		printCtlHelp()
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	request := control.Request{
+		Command: flags.Arg(0),
+		Source:  *source,
+		Pattern: strings.Join(flags.Args()[1:], " "),
+	}
+
+	if *path == "" {
+		found, err := findControl()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		*path = found
+	}
+
+	client, err := control.Dial(*path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	switch request.Command {
+	case control.CommandStatus:
+		err = printStatus(client, request)
+	case control.CommandTail:
+		err = tail(client, request)
+	default:
+		_, err = client.Send(request)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+// The socket of the only monique running
+func findControl() (string, error) {
+	running, err := control.Running()
+	if err != nil {
+		return "", err
+	}
+
+	switch len(running) {
+	case 0:
+		return "", errors.New("no monique is running, or it listens on a socket given with -control")
+	case 1:
+		return running[0], nil
+	}
+
+	return "", fmt.Errorf("several monique are running, choose one with -control:\n  %s", strings.Join(running, "\n  "))
+}
+
+// Prints how the commands are doing, one command per line
+func printStatus(client *control.Client, request control.Request) error {
+	response, err := client.Send(request)
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tSTATE\tRUNS\tLAST EXIT\tLAST ERROR\tCOMMAND")
+	for _, source := range response.Sources {
+		exit := "-"
+		if source.Exit != nil {
+			exit = source.Exit.Short()
+		}
+		lastError := "-"
+		if source.LastError != "" {
+			lastError = source.LastError
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\t%s\n", source.Name, source.State, source.Runs, exit, lastError, source.Command)
+	}
+
+	return table.Flush()
+}
+
+// Writes the output of the commands as it comes in, like -plain,
+// until monique exits
+func tail(client *control.Client, request control.Request) error {
+	// the names are only written when telling commands apart
+	status, err := client.Send(control.Request{Command: control.CommandStatus, Source: request.Source})
+	if err != nil {
+		return err
+	}
+	if _, err := client.Send(request); err != nil {
+		return err
+	}
+
+	stdoutIsTerminal := term.IsTerminal(int(os.Stdout.Fd()))
+	writer, err := plain.NewWriter(os.Stdout, plain.Options{
+		Prefix:       len(status.Sources) > 1,
+		StripEscapes: !stdoutIsTerminal,
+	})
+	if err != nil {
+		return err
+	}
+
+	renderers := make(map[string]*plain.Renderer)
+	return client.Events(func(event control.Event) {
+		renderer, ok := renderers[event.Source]
+		if !ok {
+			renderer = plain.NewRenderer(writer, event.Source)
+			renderers[event.Source] = renderer
+		}
+
+		replay(renderer, event)
+	})
+}
+
+// Passes an event on to a listener, as the mediator of the command did
+func replay(listener mediator.MediatorListener, event control.Event) {
+	switch event.Type {
+	case control.EventStart:
+		listener.OnStart(event.Command)
+	case control.EventStep:
+		if event.Step != nil {
+			listener.OnStep(*event.Step)
+		}
+	case control.EventOutput:
+		listener.OnOutput(event.Output)
+	case control.EventExit:
+		if event.Status != nil {
+			listener.OnExit(*event.Status)
+		}
+	case control.EventKill:
+		listener.OnKill()
+	case control.EventError:
+		listener.OnError(errors.New(event.Error))
+	case control.EventRestart:
+		if event.Restart != nil {
+			listener.OnRestartScheduled(*event.Restart)
+		}
+	}
+}
+
+func printCtlHelp() {
+	fmt.Fprint(os.Stderr, `monique ctl - drive a running monique through its control socket
+
+Usage:  monique ctl [-control <path>] [-source <name>] <request> [<pattern>]
+
+Requests:
+  restart              restart the commands
+  stop                 stop the commands
+  set-filter <pattern> filter the pane with this pattern, or clear its filters
+  set-search <pattern> search this pattern in the pane, or clear the search
+  status               tell how the commands are doing
+  tail                 write the output of the commands as it comes in
+
+Examples:
+  - Restart the api from an editor, once a file is saved:
+    $ monique ctl -source api restart
+
+  - Show only the errors of the active pane:
+    $ monique ctl set-filter 'ERROR|panic'
+
+Options:
+`)
+}
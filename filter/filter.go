@@ -0,0 +1,117 @@
+// Package filter parses filter terms, and compiles the patterns of filters
+// and searches, the same way for the user interface, the plain output and
+// the web interface
+package filter
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Prefix of the filter terms hiding the lines they match
+const ExcludePrefix = "!"
+
+// Prefix of the filter terms that are field expressions, like
+// "?level>=warn", no regular expression starting with it
+const ExprPrefix = "?"
+
+// Field expressions need the structured log lines parsed by the user interface
+var ErrExprUnsupported = errors.New("field expressions only apply to the user interface")
+
+// A filter term: lines must match the pattern of include terms, and
+// must not match the pattern of exclude terms
+type Term struct {
+	Pattern string // a regular expression, or a field expression with its prefix
+	Exclude bool
+}
+
+// A term as typed, like "ERROR|WARN" or "!healthcheck"
+func Parse(input string) Term {
+	if pattern, ok := strings.CutPrefix(input, ExcludePrefix); ok {
+		return Term{Pattern: pattern, Exclude: true}
+	}
+
+	return Term{Pattern: input}
+}
+
+func (t Term) String() string {
+	if t.Exclude {
+		return ExcludePrefix + t.Pattern
+	}
+
+	return t.Pattern
+}
+
+// The field expression of the term, without its prefix, if it is one
+func (t Term) Expr() (string, bool) {
+	return strings.CutPrefix(t.Pattern, ExprPrefix)
+}
+
+// Compiles the pattern of a term that is not a field expression
+func (t Term) Regexp() (*regexp.Regexp, error) {
+	if _, ok := t.Expr(); ok {
+		return nil, ErrExprUnsupported
+	}
+
+	return CompilePattern(t.Pattern)
+}
+
+// MARK: - Patterns
+
+// Maximum number of compiled patterns kept
+const maxCachedPatterns = 64
+
+var (
+	patternCache   = make(map[string]*regexp.Regexp)
+	patternCacheMu sync.Mutex
+)
+
+// Compiles a filter or search pattern, case insensitive unless it has
+// upper case letters. Compiled patterns are cached, as the same ones are
+// applied to every new line
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	patternCacheMu.Lock()
+	defer patternCacheMu.Unlock()
+
+	if reg, ok := patternCache[pattern]; ok {
+		return reg, nil
+	}
+
+	source := addTopLevelCapture(pattern)
+	if !shouldCaseSensitive(source) {
+		source = makeInsensitive(source)
+	}
+
+	reg, err := regexp.Compile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(patternCache) >= maxCachedPatterns {
+		clear(patternCache)
+	}
+	patternCache[pattern] = reg
+
+	return reg, nil
+}
+
+func shouldCaseSensitive(pattern string) bool {
+	for _, r := range pattern {
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func makeInsensitive(pattern string) string {
+	return "(?i)" + pattern
+}
+
+func addTopLevelCapture(pattern string) string {
+	return "(" + pattern + ")"
+}
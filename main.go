@@ -3,13 +3,26 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"golang.org/x/term"
+
+	"github.com/gaelph/monique/control"
 	"github.com/gaelph/monique/mediator"
+	"github.com/gaelph/monique/plain"
+	"github.com/gaelph/monique/record"
 	"github.com/gaelph/monique/runner"
 	"github.com/gaelph/monique/viewport"
 	"github.com/gaelph/monique/watcher"
+	"github.com/gaelph/monique/web"
 )
 
 type watchTargets []string
@@ -22,65 +35,575 @@ func (w *watchTargets) Set(value string) error {
 	return nil
 }
 
+// Glob patterns given with -include or -ignore
+type patternList []string
+
+func (l *patternList) String() string {
+	return strings.Join(*l, ",")
+}
+func (l *patternList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// A command given with -cmd name=command
+type namedCommand struct {
+	name    string
+	command []string
+	steps   [][]string // commands to run before it
+	env     []string   // environment variables added to its own, as KEY=value
+}
+
+type namedCommands []namedCommand
+
+// Commands given with -pre, run before the command
+type preSteps [][]string
+
+func (s *preSteps) String() string {
+	steps := make([]string, len(*s))
+	for i, step := range *s {
+		steps[i] = strings.Join(step, " ")
+	}
+	return strings.Join(steps, " && ")
+}
+func (s *preSteps) Set(value string) error {
+	step, err := runner.ParseCommand(value)
+	if err != nil {
+		return err
+	}
+
+	*s = append(*s, step)
+	return nil
+}
+
+func (c *namedCommands) String() string {
+	names := make([]string, len(*c))
+	for i, command := range *c {
+		names[i] = command.name
+	}
+	return strings.Join(names, ", ")
+}
+func (c *namedCommands) Set(value string) error {
+	name, line, found := strings.Cut(value, "=")
+	if !found || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected name=command, got %q", value)
+	}
+
+	command, err := runner.ParseCommand(line)
+	if err != nil {
+		return err
+	}
+
+	*c = append(*c, namedCommand{name: strings.TrimSpace(name), command: command})
+	return nil
+}
+
+// Values of the command line flags, or of the configuration file
+type settings struct {
+	watchList   watchTargets
+	delay       int
+	grace       time.Duration
+	restart     string
+	maxRestarts int
+	backoff     runner.Backoff
+	exts        string
+	includes    patternList
+	ignores     patternList
+	noIgnore    bool          // whether .gitignore and .ignore files are disregarded
+	poll        time.Duration // 0 to rely on file system events
+	noHash      bool          // whether every write is a change, even of identical content
+	commands    namedCommands
+	steps       preSteps
+	context     int    // lines of context before and after matching lines, unless -A or -B are given
+	diffIgnore  string // pattern of what is ignored when comparing runs
+	plain       bool   // whether the output is written as plain lines, without the user interface
+	prefix      bool   // plain output: whether lines are prefixed with the name of their command
+	timestamps  bool   // plain output: whether lines are prefixed with the time they were received
+	control     string // path of the control socket, the default one when empty
+	http        string // address the web interface is served on, none when empty
+	record      string // path of the file the session is recorded to, if not empty
+	configPath  string
+	showHelp    bool
+	env         []string // for the command given as arguments
+	options     viewport.Options
+}
+
 var p *viewport.Program
 
 var w *watcher.Watcher
 
 func main() {
-	var watchList watchTargets
-	var delay int
-	var exts string
 	var extensionList []string
 	var command []string
-	var showHelp bool
-
-	flag.Var(&watchList, "watch", "path to a directory to watch")
-	flag.Var(&watchList, "w", "shorthand for -watch")
-	flag.StringVar(&exts, "exts", "", "file extensions")
-	flag.StringVar(&exts, "e", "", "shorthand for -exts")
-	flag.IntVar(&delay, "delay", 100, "delay in ms")
-	flag.IntVar(&delay, "d", 100, "shorthand for -delay")
-	flag.BoolVar(&showHelp, "help", false, "show help")
-	flag.BoolVar(&showHelp, "h", false, "shorthand for -help")
-
-	flag.Parse()
-	command = flag.Args()
+	s := settings{
+		backoff: runner.DefaultBackoff(),
+		options: viewport.DefaultOptions(),
+	}
+
+	flag.Var(&s.watchList, "watch", "path to a directory to watch")
+	flag.Var(&s.watchList, "w", "shorthand for -watch")
+	flag.StringVar(&s.exts, "exts", "", "file extensions")
+	flag.StringVar(&s.exts, "e", "", "shorthand for -exts")
+	flag.Var(&s.includes, "include", "glob pattern of the files to watch, like 'src/**/*.go'")
+	flag.Var(&s.ignores, "ignore", "glob pattern of the paths not to watch, like 'node_modules/' or '*_test.go'")
+	flag.BoolVar(&s.noIgnore, "no-ignore", false, "do not honor .gitignore and .ignore files")
+	flag.DurationVar(&s.poll, "poll", 0, "poll the watched files at this interval, instead of relying on file system events")
+	flag.BoolVar(&s.noHash, "no-hash", false, "restart on every write, even when the content of the file did not change")
+	flag.Var(&s.commands, "cmd", "a named command, as name=command, to run alongside others")
+	flag.Var(&s.steps, "pre", "a command that must succeed before the command is (re)started")
+	flag.IntVar(&s.delay, "delay", 100, "delay in ms")
+	flag.IntVar(&s.delay, "d", 100, "shorthand for -delay")
+	flag.DurationVar(&s.grace, "grace", runner.DefaultGracePeriod, "time given to the command to exit before it is killed")
+	flag.StringVar(&s.restart, "restart", "never", "when to restart the command after it exits: never, on-failure or always")
+	flag.IntVar(&s.maxRestarts, "max-restarts", 10, "maximum consecutive automatic restarts, 0 for unlimited")
+	flag.Var(&s.backoff, "backoff", "range of the delay between automatic restarts, doubling each attempt")
+	flag.IntVar(&s.options.Scrollback, "scrollback", viewport.DefaultScrollback, "maximum number of lines kept, 0 for unlimited")
+	flag.IntVar(&s.options.ContextAfter, "A", 0, "number of lines displayed after each line passing the filter")
+	flag.IntVar(&s.options.ContextBefore, "B", 0, "number of lines displayed before each line passing the filter")
+	flag.IntVar(&s.context, "C", 0, "number of lines displayed before and after each line passing the filter")
+	flag.BoolVar(&s.options.Structured, "structured", false, "display JSON and logfmt log lines as time, level, message and fields")
+	flag.StringVar(&s.options.ErrorFile, "errorfile", "", "write the source locations reported by each run to this file, for the quickfix list of vim")
+	flag.BoolVar(&s.options.Keep, "keep", false, "keep the output of every run, each under a header, instead of clearing it on restart")
+	flag.IntVar(&s.options.History, "history", viewport.DefaultHistory, "number of runs kept, to be displayed or compared again, 0 for unlimited")
+	flag.StringVar(&s.diffIgnore, "diff-ignore", viewport.DefaultDiffIgnore, "pattern of what is ignored when comparing the output of two runs, like timestamps")
+	flag.StringVar(&s.options.Filter, "filter", "", "only show the lines matching this pattern, or not matching it when starting with !, or passing the field expression starting with ?")
+	flag.BoolVar(&s.plain, "plain", false, "write the output as plain lines, without the user interface (default when the output is not a terminal)")
+	flag.BoolVar(&s.prefix, "prefix", false, "plain output: prefix lines with the name of their command (always with several commands)")
+	flag.BoolVar(&s.timestamps, "timestamps", false, "plain output: prefix lines with the time they were received")
+	flag.StringVar(&s.control, "control", "", "path of the control socket (default: $XDG_RUNTIME_DIR/monique-<pid>.sock)")
+	flag.StringVar(&s.http, "http", "", "serve the output to browsers on this address, like 127.0.0.1:7777")
+	flag.StringVar(&s.record, "record", "", "record the session to this file, to be replayed with monique replay")
+	flag.StringVar(&s.configPath, "config", "", "path to a configuration file (default: monique.toml or .monique.yaml, if present)")
+	flag.BoolVar(&s.showHelp, "help", false, "show help")
+	flag.BoolVar(&s.showHelp, "h", false, "shorthand for -help")
 
-	if showHelp {
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		os.Exit(runCtl(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:]))
+	}
+
+	command, profile := parseArgs(os.Args[1:])
+
+	if s.showHelp {
 		printHelp()
 		os.Exit(0)
 		return
 	}
 
-	extensionList = strings.Split(exts, ",")
+	if err := s.loadConfig(profile, &command); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	s.applyContext()
+	if err := viewport.SetDiffIgnore(s.diffIgnore); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	commands := s.commands
+	if len(command) > 0 {
+		commands = append(commands, namedCommand{
+			name:    filepath.Base(command[0]),
+			command: command,
+			steps:   s.steps,
+			env:     s.env,
+		})
+	} else if len(s.steps) > 0 {
+		fmt.Fprintln(os.Stderr, "-pre requires a command to be given as arguments")
+		os.Exit(2)
+	}
+	// the output of another command is piped into monique
+	readStdin := len(commands) == 0 && !term.IsTerminal(int(os.Stdin.Fd()))
+	if len(commands) == 0 && !readStdin {
+		printHelp()
+		os.Exit(2)
+	}
+	if err := checkNames(commands); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	restartPolicy, err := runner.ParseRestartPolicy(s.restart)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	extensionList = strings.Split(s.exts, ",")
 	for idx, ext := range extensionList {
 		extensionList[idx] = strings.TrimSpace(ext)
 	}
 
-	m := mediator.NewMediator()
+	sources := make([]viewport.Source, len(commands))
+	runners := make([]*runner.Runner, len(commands))
+	for i, c := range commands {
+		m := mediator.NewMediator()
 
-	p = viewport.NewProgram(strings.Join(command, " "), m)
-	r := runner.NewRunner(command, delay)
-	r.SetMediator(m)
+		sources[i] = viewport.Source{
+			Name:     c.name,
+			Command:  strings.Join(c.command, " "),
+			Mediator: m,
+		}
+
+		r := runner.NewRunner(c.command, s.delay)
+		r.SetGracePeriod(s.grace)
+		r.SetRestartPolicy(restartPolicy, s.backoff, s.maxRestarts)
+		r.SetPreSteps(c.steps)
+		r.SetEnv(c.env)
+		r.SetMediator(m)
+		runners[i] = r
+	}
+
+	// what runs alongside the user interface: the commands,
+	// and the reading of the standard input
+	jobs := []func(){}
+	for _, r := range runners {
+		jobs = append(jobs, r.Start)
+	}
+	if readStdin {
+		m := mediator.NewMediator()
+		sources = append(sources, viewport.Source{
+			Name:     "stdin",
+			Command:  "stdin",
+			Mediator: m,
+			Input:    true,
+		})
+		jobs = append(jobs, func() { runner.ReadInput(os.Stdin, m) })
+	}
 
-	if len(watchList) > 0 {
+	stdoutIsTerminal := term.IsTerminal(int(os.Stdout.Fd()))
+	usePlain := s.plain || !stdoutIsTerminal
+
+	// the source locations are only found by the user interface, the one
+	// of the configuration file only applying to it
+	if usePlain && flagGiven("errorfile") {
+		fmt.Fprintln(os.Stderr, "-errorfile requires the user interface, and can't be used with -plain or when the output is not a terminal")
+		os.Exit(2)
+	}
+
+	renderers := []*plain.Renderer{}
+	if usePlain {
+		// the debug log would be mixed with the output
+		log.SetOutput(io.Discard)
+
+		writer, err := plain.NewWriter(os.Stdout, plain.Options{
+			Prefix:       s.prefix || len(sources) > 1,
+			Timestamps:   s.timestamps,
+			Filter:       s.options.Filter,
+			StripEscapes: !stdoutIsTerminal,
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+
+		for _, source := range sources {
+			renderer := plain.NewRenderer(writer, source.Name)
+			source.Mediator.AddListener(renderer)
+			renderers = append(renderers, renderer)
+		}
+	} else {
+		if readStdin {
+			// the keys can't be read from the pipe
+			tty, err := os.Open("/dev/tty")
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			defer tty.Close()
+			s.options.Input = tty
+		}
+
+		p = viewport.NewProgram(s.options, sources...)
+	}
+
+	server := startControl(s.control, sources)
+	if server != nil {
+		defer server.Close()
+	}
+
+	var webServer *web.Server
+	if s.http != "" {
+		webServer = startWeb(s.http, sources)
+		defer webServer.Close()
+	}
+
+	var recorder *record.Recorder
+	if s.record != "" {
+		recorder = startRecording(s.record, sources)
+		defer stopRecording(recorder)
+	}
+
+	if len(s.watchList) > 0 {
 		// creates a new file watcher
-		w = watcher.NewWatcher(watchList, extensionList)
+		w = watcher.NewWatcher(s.watchList, extensionList)
 		defer w.Close()
 
-		onChange := func(path string, change string) {
-			p.Append(fmt.Sprintf("Change detected[%s]: %s\n", change, path))
-			m.SendRequestRestart()
+		if err := w.SetIncludes(s.includes); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if err := w.SetIgnores(s.ignores); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		w.SetUseIgnoreFiles(!s.noIgnore)
+		w.SetPollInterval(s.poll)
+		w.SetCompareContent(!s.noHash)
+		w.SetStatusListener(func(status watcher.Status) {
+			if p != nil {
+				p.SetWatchStatus(status.Backend, status.Watches, status.Suppressed)
+			}
+		})
+
+		onChange := func(changes []watcher.Change) {
+			changeSet := mediator.ChangeSet{}
+			for _, change := range changes {
+				changeSet.Add(change.Path, change.Kind)
+			}
+
+			for _, source := range sources {
+				source.Mediator.SendFilesChanged(changeSet)
+			}
 		}
 		w.SetChangeListener(onChange)
 
 		w.Start()
 	}
 
-	go r.Start()
+	if usePlain {
+		// the commands are run once, unless something restarts them,
+		// and the standard input is read to its end
+		once := len(s.watchList) == 0 && restartPolicy == runner.RestartNever || len(runners) == 0
+		code := runPlain(jobs, runners, renderers, once)
+		if w != nil {
+			w.Close()
+		}
+		if server != nil {
+			server.Close()
+		}
+		if webServer != nil {
+			webServer.Close()
+		}
+		if recorder != nil {
+			stopRecording(recorder)
+		}
+		os.Exit(code)
+	}
+
+	for _, job := range jobs {
+		go job()
+	}
 	p.Run()
 
-	r.Stop()
+	stopAll(runners)
+}
+
+// Runs the jobs without the user interface, until they are all done
+// when the commands run once, or until monique is interrupted.
+// Returns the exit code of monique: that of the first command that failed
+func runPlain(jobs []func(), runners []*runner.Runner, renderers []*plain.Renderer, once bool) int {
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+
+	started := sync.WaitGroup{}
+	for _, job := range jobs {
+		started.Add(1)
+		go func(job func()) {
+			defer started.Done()
+			job()
+		}(job)
+	}
+
+	if !once {
+		<-interrupted
+		stopAll(runners)
+		return 0
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		started.Wait()
+		// the commands may have been restarted through the control socket
+		for _, r := range runners {
+			r.Wait()
+		}
+		close(exited)
+	}()
+
+	select {
+	case <-exited:
+	case <-interrupted:
+		stopAll(runners)
+	}
+
+	for _, renderer := range renderers {
+		if code := renderer.ExitCode(); code != 0 {
+			return code
+		}
+	}
+
+	return 0
+}
+
+// Listens on the control socket at path, or at the default path when empty.
+// Returns nil when it could not, which is only fatal when path was given
+func startControl(path string, sources []viewport.Source) *control.Server {
+	given := path != ""
+	if !given {
+		var err error
+		if path, err = control.DefaultPath(); err != nil {
+			log.Println("could not listen on the control socket:", err)
+			return nil
+		}
+	}
+
+	controlSources := make([]control.Source, len(sources))
+	for i, source := range sources {
+		controlSources[i] = control.Source{
+			Name:     source.Name,
+			Command:  source.Command,
+			Mediator: source.Mediator,
+		}
+	}
+
+	// filters and searches are left to the user interface, if any
+	var view control.View
+	if p != nil {
+		view = p
+	}
+
+	server, err := control.NewServer(path, view, controlSources...)
+	if err != nil {
+		if given {
+			fmt.Fprintln(os.Stderr, "control:", err)
+			os.Exit(2)
+		}
+		log.Println("could not listen on the control socket:", err)
+		return nil
+	}
+	go server.Serve()
+
+	return server
+}
+
+// Serves the output of the commands to browsers on addr
+func startWeb(addr string, sources []viewport.Source) *web.Server {
+	webSources := make([]web.Source, len(sources))
+	for i, source := range sources {
+		webSources[i] = web.Source{
+			Name:     source.Name,
+			Command:  source.Command,
+			Mediator: source.Mediator,
+		}
+	}
+
+	server, err := web.NewServer(addr, webSources...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "http:", err)
+		os.Exit(2)
+	}
+	go server.Serve()
+
+	return server
+}
+
+// Records the events of the commands to the file at path
+func startRecording(path string, sources []viewport.Source) *record.Recorder {
+	recordSources := make([]record.Source, len(sources))
+	for i, source := range sources {
+		recordSources[i] = record.Source{
+			Name:     source.Name,
+			Command:  source.Command,
+			Input:    source.Input,
+			Mediator: source.Mediator,
+		}
+	}
+
+	recorder, err := record.NewRecorder(path, recordSources...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "record:", err)
+		os.Exit(2)
+	}
+
+	return recorder
+}
+
+// Closes the recording, telling whether some of it could not be written
+func stopRecording(recorder *record.Recorder) {
+	if err := recorder.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// Stops every command, and returns once they are all gone
+func stopAll(runners []*runner.Runner) {
+	stopped := sync.WaitGroup{}
+	for _, r := range runners {
+		stopped.Add(1)
+		go func(r *runner.Runner) {
+			defer stopped.Done()
+			r.Stop()
+		}(r)
+	}
+	stopped.Wait()
+}
+
+// Parses the flags, and returns the command with the name of the profile
+// selected with @profile, if any.
+// The profile can be given anywhere before the command.
+func parseArgs(args []string) (command []string, profile string) {
+	flag.CommandLine.Parse(args)
+	command = flag.Args()
+
+	for len(command) > 0 && strings.HasPrefix(command[0], "@") {
+		profile = strings.TrimPrefix(command[0], "@")
+		flag.CommandLine.Parse(command[1:])
+		command = flag.Args()
+	}
+
+	return command, profile
+}
+
+// Whether the flag was given on the command line
+func flagGiven(name string) bool {
+	given := false
+	flag.Visit(func(f *flag.Flag) {
+		given = given || f.Name == name
+	})
+
+	return given
+}
+
+// -A and -B take precedence over -C, whatever their order
+func (s *settings) applyContext() {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	if !given["A"] {
+		s.options.ContextAfter = s.context
+	}
+	if !given["B"] {
+		s.options.ContextBefore = s.context
+	}
+}
+
+// Commands are told apart by their names
+func checkNames(commands namedCommands) error {
+	seen := make(map[string]bool)
+	for _, c := range commands {
+		if seen[c.name] {
+			return fmt.Errorf("several commands are named %q, use -cmd name=command to name them", c.name)
+		}
+		seen[c.name] = true
+	}
+
+	return nil
 }
 
 func printHelp() {
@@ -89,6 +612,11 @@ func printHelp() {
 Usage:  monique [options] <command>
   monique <command>
   monique [[-watch <path>]... [-exts <ext-list>] [-delay <delay>]  <command>
+  monique [-cmd <name>=<command>]... [<command>]
+  monique [-config <path>] [@<profile>] [options] [<command>]
+  monique ctl [-control <path>] [-source <name>] <request> [<pattern>]
+  monique replay <file> [-speed <speed>] [-keep]
+  <command> | monique [options]
 
 Examples:
   - Restart a command when any js or css file changes in a single directory:
@@ -97,9 +625,70 @@ Examples:
   - Run 'make' when any c, cpp, or header file changes in two directories:
     $ monique -watch ./src -watch ./include -exts .c,.cpp,.h,.hpp make
 
+  - Run the tests when a go file changes, except in the vendor directory:
+    $ monique -watch . -include '*.go' -ignore 'vendor/' go test ./...
+
+  - Lint only the files that changed:
+    $ monique -watch . -exts .js npx eslint {changed}
+
+  - Watch a directory mounted over NFS, or in a container, where file system
+    events are not available:
+    $ monique -watch ./src -poll 500ms make
+
+  - Keep a development server running, restarting it when it crashes:
+    $ monique -restart on-failure -backoff 1s..1m go run ./cmd/server
+
+  - Run an API and its frontend side by side, in their own tabs:
+    $ monique -watch . -cmd 'api=go run ./cmd/api' -cmd 'web=npm run dev'
+
+  - Build, and restart the server only if the build succeeds:
+    $ monique -watch . -exts .go -pre 'go build -o bin/app .' ./bin/app
+
+  - Show the stack traces following panics, filtering with "panic":
+    $ monique -A 20 go test ./...
+
+  - Display the JSON logs of a service by their fields, and filter them with
+    '?level>=warn and latency_ms>200':
+    $ monique -structured go run ./cmd/server
+
+  - Keep the quickfix list of vim up to date with the build errors, loaded
+    with :cfile errors.err
+    $ monique -watch . -exts .go -errorfile errors.err go build ./...
+
+  - Keep a continuous log of the server across restarts, each run under
+    a header, jumping between runs with [ and ]:
+    $ monique -watch . -exts .go -keep go run ./cmd/server
+
+  - Compare the output of the tests before and after a change, ignoring
+    the lines that only differ by their request ids, with [h] then [d]:
+    $ monique -watch . -diff-ignore 'req-[0-9a-f]+' go test ./...
+
+  - Run the tests in CI, where the output is not a terminal, showing only
+    the failures, and exiting with the exit code of the tests:
+    $ monique -plain -timestamps -filter 'FAIL|panic' go test ./...
+
+  - Restart the commands of a running monique from an editor or a script,
+    see monique ctl -help:
+    $ monique ctl restart
+
+  - Follow the logs of the server from a browser, at http://127.0.0.1:7777,
+    while pairing:
+    $ monique -http 127.0.0.1:7777 go run ./cmd/server
+
+  - Record a session with a flaky failure, then replay it four times faster,
+    filtering and searching it, see monique replay -help:
+    $ monique -watch . -record flaky.monique go test ./...
+    $ monique replay flaky.monique -speed 4x
+
+  - Use the "dev" profile of ./monique.toml:
+    $ monique @dev
+
   - Filter and search on a tail -f call, live:
     $ monique tail -f /var/log/nginx/access.log
 
+  - Filter and search the logs of a pod, piped into monique:
+    $ kubectl logs -f deploy/api | monique
+
 Options:
 `)
 	flag.PrintDefaults()
@@ -0,0 +1,64 @@
+package mediator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// How many files are named in the description of a change set
+const shownChanges = 3
+
+// Files that changed, since the command was last started
+type ChangeSet struct {
+	Files []string // paths, in the order they first changed
+	Kinds []string // like "write" or "rename", in the order they first happened
+}
+
+// Records a change of path, of the given kind
+func (c *ChangeSet) Add(path string, kind string) {
+	if !slices.Contains(c.Files, path) {
+		c.Files = append(c.Files, path)
+	}
+	if !slices.Contains(c.Kinds, kind) {
+		c.Kinds = append(c.Kinds, kind)
+	}
+}
+
+// Records the changes of other, after those already recorded
+func (c *ChangeSet) Merge(other ChangeSet) {
+	for _, path := range other.Files {
+		if !slices.Contains(c.Files, path) {
+			c.Files = append(c.Files, path)
+		}
+	}
+	for _, kind := range other.Kinds {
+		if !slices.Contains(c.Kinds, kind) {
+			c.Kinds = append(c.Kinds, kind)
+		}
+	}
+}
+
+func (c ChangeSet) Empty() bool {
+	return len(c.Files) == 0
+}
+
+// Like "3 files changed: a.go, b.go, c.go" or "5 files changed: a.go, b.go, c.go, …"
+func (c ChangeSet) String() string {
+	noun := "files"
+	if len(c.Files) == 1 {
+		noun = "file"
+	}
+
+	return fmt.Sprintf("%d %s changed: %s", len(c.Files), noun, c.Names())
+}
+
+// Like "a.go, b.go, c.go", or "a.go, b.go, c.go, …" when more files changed
+func (c ChangeSet) Names() string {
+	shown := c.Files
+	if len(shown) > shownChanges {
+		shown = append(slices.Clone(shown[:shownChanges]), "…")
+	}
+
+	return strings.Join(shown, ", ")
+}
@@ -5,8 +5,14 @@ type MediatorListener interface {
 	OnError(err error)
 	OnKill()
 	OnStop()
+	OnExit(status ExitStatus)
+	OnStep(step Step)
 	OnOutput(output string)
 	OnRequestRestart()
+	OnRequestStop()
+	OnFilesChanged(changes ChangeSet)
+	OnRestartScheduled(restart ScheduledRestart)
+	OnCancelRestart()
 }
 
 type Mediator interface {
@@ -14,8 +20,14 @@ type Mediator interface {
 	SendError(err error)
 	SendKill()
 	SendStop()
+	SendExit(status ExitStatus)
+	SendStep(step Step)
 	SendOutput(output string)
 	SendRequestRestart()
+	SendRequestStop()
+	SendFilesChanged(changes ChangeSet)
+	SendRestartScheduled(restart ScheduledRestart)
+	SendCancelRestart()
 	AddListener(listener MediatorListener)
 }
 
@@ -55,6 +67,18 @@ func (mediator *mediator) SendStop() {
 	}
 }
 
+func (mediator *mediator) SendExit(status ExitStatus) {
+	for _, listener := range mediator.listeners {
+		listener.OnExit(status)
+	}
+}
+
+func (mediator *mediator) SendStep(step Step) {
+	for _, listener := range mediator.listeners {
+		listener.OnStep(step)
+	}
+}
+
 func (mediator *mediator) SendOutput(output string) {
 	for _, listener := range mediator.listeners {
 		listener.OnOutput(output)
@@ -66,3 +90,27 @@ func (mediator *mediator) SendRequestRestart() {
 		listener.OnRequestRestart()
 	}
 }
+
+func (mediator *mediator) SendRequestStop() {
+	for _, listener := range mediator.listeners {
+		listener.OnRequestStop()
+	}
+}
+
+func (mediator *mediator) SendFilesChanged(changes ChangeSet) {
+	for _, listener := range mediator.listeners {
+		listener.OnFilesChanged(changes)
+	}
+}
+
+func (mediator *mediator) SendRestartScheduled(restart ScheduledRestart) {
+	for _, listener := range mediator.listeners {
+		listener.OnRestartScheduled(restart)
+	}
+}
+
+func (mediator *mediator) SendCancelRestart() {
+	for _, listener := range mediator.listeners {
+		listener.OnCancelRestart()
+	}
+}
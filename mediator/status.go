@@ -0,0 +1,92 @@
+package mediator
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// Describes how a run of a command ended
+type ExitStatus struct {
+	Command    string         // the command that was run
+	Code       int            // exit code, -1 if the process was terminated by a signal
+	Signal     syscall.Signal // the signal that terminated the process, if any
+	Duration   time.Duration  // wall-clock duration of the run
+	UserTime   time.Duration  // user CPU time
+	SystemTime time.Duration  // system CPU time
+	MaxRSS     int64          // maximum resident set size, in kilobytes
+}
+
+// Whether the command exited with a 0 code
+func (s ExitStatus) Success() bool {
+	return s.Code == 0
+}
+
+// Whether the command was terminated by a signal
+func (s ExitStatus) Signaled() bool {
+	return s.Code < 0
+}
+
+// Short description of the outcome, like "exit 1" or "SIGTERM"
+func (s ExitStatus) Short() string {
+	if s.Signaled() {
+		return signalName(s.Signal)
+	}
+
+	return fmt.Sprintf("exit %d", s.Code)
+}
+
+func (s ExitStatus) String() string {
+	if s.Signaled() {
+		return fmt.Sprintf("terminated by %s", signalName(s.Signal))
+	}
+
+	return fmt.Sprintf("exited with code %d", s.Code)
+}
+
+func signalName(sig syscall.Signal) string {
+	switch sig {
+	case syscall.SIGHUP:
+		return "SIGHUP"
+	case syscall.SIGINT:
+		return "SIGINT"
+	case syscall.SIGQUIT:
+		return "SIGQUIT"
+	case syscall.SIGABRT:
+		return "SIGABRT"
+	case syscall.SIGKILL:
+		return "SIGKILL"
+	case syscall.SIGSEGV:
+		return "SIGSEGV"
+	case syscall.SIGPIPE:
+		return "SIGPIPE"
+	case syscall.SIGTERM:
+		return "SIGTERM"
+	}
+
+	return fmt.Sprintf("signal %d", int(sig))
+}
+
+// A step of a pipeline, the command itself being the last one
+type Step struct {
+	Index   int    // starting at 1
+	Total   int    // number of steps, including the command
+	Command string // the command run by this step
+}
+
+// A pre-step that failed, leaving the previous run of the command going on
+type StepError struct {
+	Step   Step
+	Status ExitStatus // how the step exited
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %d/%d %s", e.Step.Index, e.Step.Total, e.Status)
+}
+
+// An automatic restart waiting for its backoff delay to expire
+type ScheduledRestart struct {
+	At          time.Time // when the command will be restarted
+	Attempt     int       // the number of this attempt, starting at 1
+	MaxAttempts int       // 0 when unlimited
+}
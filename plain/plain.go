@@ -0,0 +1,218 @@
+// Package plain writes the output of commands as plain lines, without
+// the user interface, for CI, ssh sessions without a terminal, and pipes
+package plain
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaelph/monique/filter"
+	"github.com/gaelph/monique/mediator"
+)
+
+// Settings of the plain output
+type Options struct {
+	Prefix       bool   // whether lines are prefixed with the name of their command
+	Timestamps   bool   // whether lines are prefixed with the time they were received
+	Filter       string // pattern of the output lines written, or not written when starting with "!"
+	StripEscapes bool   // whether colors and other escape sequences are removed
+}
+
+// Writes the lines of every command, one at a time
+type Writer struct {
+	out     io.Writer
+	options Options
+	filter  *regexp.Regexp // nil when every line is written
+	exclude bool           // whether the lines matching the filter are left out
+	mu      sync.Mutex
+}
+
+func NewWriter(out io.Writer, options Options) (*Writer, error) {
+	w := &Writer{out: out, options: options}
+
+	term := filter.Parse(options.Filter)
+	if term.Pattern != "" {
+		reg, err := term.Regexp()
+		if err != nil {
+			return nil, fmt.Errorf("filter: %w", err)
+		}
+		w.filter, w.exclude = reg, term.Exclude
+	}
+
+	return w, nil
+}
+
+// Escape sequences: CSI sequences like colors, OSC sequences like
+// hyperlinks, and two character sequences
+var escapeSequence = regexp.MustCompile(`\x1b(\[[0-?]*[ -/]*[@-~]|\][^\a\x1b]*(\a|\x1b\\)?|.)`)
+
+// The visible text of a line, without its colors and other escape sequences
+func StripEscapes(line string) string {
+	return escapeSequence.ReplaceAllString(line, "")
+}
+
+// Writes a line of output of the named command, if it passes the filter
+func (w *Writer) writeOutput(name string, line string) {
+	visible := StripEscapes(line)
+	if w.filter != nil && w.filter.MatchString(visible) == w.exclude {
+		return
+	}
+	if w.options.StripEscapes {
+		line = visible
+	}
+
+	w.writeLine(name, line)
+}
+
+// Writes a line, prefixed with the time and the name of its command
+// when asked to
+func (w *Writer) writeLine(name string, line string) {
+	prefix := ""
+	if w.options.Timestamps {
+		prefix += time.Now().Format("15:04:05.000") + " "
+	}
+	if w.options.Prefix && name != "" {
+		prefix += fmt.Sprintf("[%s] ", name)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	fmt.Fprintln(w.out, prefix+line)
+}
+
+// Exit code when the command could not be started, like shells
+// when a command is not found
+const notStartedCode = 127
+
+// Exit code of the command once it exited, like shells: its own,
+// or 128 plus the number of the signal that terminated it.
+// A run whose exit is unknown, as its process never started, did not run
+func ExitCode(status mediator.ExitStatus) int {
+	if status.Signaled() {
+		if status.Signal == 0 {
+			return notStartedCode
+		}
+		return 128 + int(status.Signal)
+	}
+
+	return status.Code
+}
+
+// Writes the output of a command, and how its runs start and end.
+// Implements mediator.MediatorListener
+type Renderer struct {
+	writer  *Writer
+	name    string
+	partial string // incomplete last line of output
+	code    int    // exit code of the last run
+	mu      sync.Mutex
+}
+
+func NewRenderer(writer *Writer, name string) *Renderer {
+	return &Renderer{writer: writer, name: name}
+}
+
+// Exit code of the last run of the command, 0 if it never ran
+func (r *Renderer) ExitCode() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.code
+}
+
+// Writes the incomplete last line of output, if any
+func (r *Renderer) flush() {
+	r.mu.Lock()
+	partial := r.partial
+	r.partial = ""
+	r.mu.Unlock()
+
+	if partial != "" {
+		r.writer.writeOutput(r.name, partial)
+	}
+}
+
+// MARK: - MediatorListener
+
+func (r *Renderer) OnStart(command string) {
+	r.flush()
+	r.writer.writeLine(r.name, fmt.Sprintf("Starting %s", command))
+}
+
+func (r *Renderer) OnError(err error) {
+	// a failing pre-step fails the pipeline like the command would
+	code := notStartedCode
+	var stepErr *mediator.StepError
+	if errors.As(err, &stepErr) {
+		code = ExitCode(stepErr.Status)
+	}
+
+	r.mu.Lock()
+	r.code = code
+	r.mu.Unlock()
+
+	r.writer.writeLine(r.name, fmt.Sprintf("Error: %s", err))
+}
+
+func (r *Renderer) OnKill() {
+	r.writer.writeLine(r.name, "Process killed")
+}
+
+func (r *Renderer) OnStop() {
+	r.flush()
+}
+
+func (r *Renderer) OnExit(status mediator.ExitStatus) {
+	r.flush()
+
+	r.mu.Lock()
+	r.code = ExitCode(status)
+	r.mu.Unlock()
+
+	r.writer.writeLine(r.name, fmt.Sprintf("── %s · %s ──", status, status.Duration.Round(time.Millisecond)))
+}
+
+func (r *Renderer) OnStep(step mediator.Step) {
+	r.flush()
+	r.writer.writeLine(r.name, fmt.Sprintf("── step %d/%d · %s ──", step.Index, step.Total, step.Command))
+}
+
+func (r *Renderer) OnOutput(output string) {
+	r.mu.Lock()
+	lines := strings.Split(r.partial+output, "\n")
+	r.partial = lines[len(lines)-1]
+	r.mu.Unlock()
+
+	for _, line := range lines[:len(lines)-1] {
+		// lines from the pty end with \r\n
+		r.writer.writeOutput(r.name, strings.TrimSuffix(line, "\r"))
+	}
+}
+
+func (r *Renderer) OnRequestRestart() {
+}
+
+func (r *Renderer) OnRequestStop() {
+}
+
+func (r *Renderer) OnFilesChanged(changes mediator.ChangeSet) {
+}
+
+func (r *Renderer) OnRestartScheduled(restart mediator.ScheduledRestart) {
+	attempt := fmt.Sprintf("%d", restart.Attempt)
+	if restart.MaxAttempts > 0 {
+		attempt += fmt.Sprintf("/%d", restart.MaxAttempts)
+	}
+
+	delay := time.Until(restart.At).Round(100 * time.Millisecond)
+	r.writer.writeLine(r.name, fmt.Sprintf("Restarting in %s (attempt %s)", delay, attempt))
+}
+
+func (r *Renderer) OnCancelRestart() {
+}
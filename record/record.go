@@ -0,0 +1,218 @@
+// Package record writes what happens to the commands to a file, as it
+// happens, and replays it later
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+// Version of the format of the files
+const formatVersion = 1
+
+// First line of a file: what was recorded
+type Header struct {
+	Version int       `json:"version"`
+	Started time.Time `json:"started"` // when the recording started
+	Sources []Source  `json:"sources"`
+}
+
+// A command whose events are recorded
+type Source struct {
+	Name     string            `json:"name"`
+	Command  string            `json:"command"`
+	Input    bool              `json:"input,omitempty"` // whether it is the standard input
+	Mediator mediator.Mediator `json:"-"`
+}
+
+// Types of events
+const (
+	EventStart         = "start"
+	EventError         = "error"
+	EventKill          = "kill"
+	EventStop          = "stop"
+	EventExit          = "exit"
+	EventStep          = "step"
+	EventOutput        = "output"
+	EventFilesChanged  = "files"
+	EventRestart       = "restart" // an automatic restart was scheduled
+	EventCancelRestart = "cancel-restart"
+)
+
+// What happened to a command, on a line of its own after the header,
+// like {"t":1520000000,"source":"api","type":"output","output":"ok\r\n"}
+type Event struct {
+	Time    time.Duration              `json:"t"` // since the recording started
+	Source  string                     `json:"source"`
+	Type    string                     `json:"type"`
+	Command string                     `json:"command,omitempty"` // start: the command line
+	Output  string                     `json:"output,omitempty"`  // output: the bytes received
+	Error   string                     `json:"error,omitempty"`   // error: why the command could not start
+	Status  *mediator.ExitStatus       `json:"status,omitempty"`  // exit: how the run ended
+	Step    *mediator.Step             `json:"step,omitempty"`    // step: the step starting
+	Changes *mediator.ChangeSet        `json:"changes,omitempty"` // files: the files that changed
+	Restart *mediator.ScheduledRestart `json:"restart,omitempty"` // restart: when the command restarts
+}
+
+// Writes the events of the commands to a file
+type Recorder struct {
+	file    *os.File
+	encoder *json.Encoder
+	started time.Time
+	mu      sync.Mutex
+	err     error // the first error writing the file
+}
+
+// Creates the file at path, replacing it, and records the events
+// of the sources in it
+func NewRecorder(path string, sources ...Source) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		file:    file,
+		encoder: json.NewEncoder(file),
+		started: time.Now(),
+	}
+
+	header := Header{Version: formatVersion, Started: r.started, Sources: sources}
+	if err := r.encoder.Encode(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	for _, source := range sources {
+		source.Mediator.AddListener(&listener{recorder: r, source: source.Name})
+	}
+
+	return r, nil
+}
+
+// Writes an event, timestamped
+func (r *Recorder) write(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	event.Time = time.Since(r.started)
+	// the file is complete up to the last event, should monique be killed
+	r.err = r.encoder.Encode(event)
+}
+
+// Closes the file. Returns the first error writing it, if any
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.file.Close(); r.err == nil && err != nil {
+		r.err = err
+	}
+	if r.err != nil {
+		return fmt.Errorf("record: %w", r.err)
+	}
+
+	return nil
+}
+
+// Records the events of a command.
+// Implements mediator.MediatorListener
+type listener struct {
+	recorder *Recorder
+	source   string
+	mu       sync.Mutex
+	partial  string // incomplete last character of the output
+}
+
+// Number of bytes of the incomplete character output ends with, if any
+func incompleteRune(output string) int {
+	for i := len(output) - 1; i >= 0 && i >= len(output)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(output[i]) {
+			if !utf8.FullRuneInString(output[i:]) {
+				return len(output) - i
+			}
+			break
+		}
+	}
+
+	return 0
+}
+
+// Writes the incomplete last character of the output, if any
+func (l *listener) flush() {
+	l.mu.Lock()
+	partial := l.partial
+	l.partial = ""
+	l.mu.Unlock()
+
+	if partial != "" {
+		l.recorder.write(Event{Source: l.source, Type: EventOutput, Output: partial})
+	}
+}
+
+func (l *listener) OnStart(command string) {
+	l.recorder.write(Event{Source: l.source, Type: EventStart, Command: command})
+}
+
+func (l *listener) OnError(err error) {
+	l.recorder.write(Event{Source: l.source, Type: EventError, Error: err.Error()})
+}
+
+func (l *listener) OnKill() {
+	l.recorder.write(Event{Source: l.source, Type: EventKill})
+}
+
+func (l *listener) OnStop() {
+	l.flush()
+	l.recorder.write(Event{Source: l.source, Type: EventStop})
+}
+
+func (l *listener) OnExit(status mediator.ExitStatus) {
+	l.flush()
+	l.recorder.write(Event{Source: l.source, Type: EventExit, Status: &status})
+}
+
+func (l *listener) OnStep(step mediator.Step) {
+	l.recorder.write(Event{Source: l.source, Type: EventStep, Step: &step})
+}
+
+func (l *listener) OnOutput(output string) {
+	// a character split between two reads is written whole, with the
+	// second one, as JSON strings only hold whole characters
+	l.mu.Lock()
+	output = l.partial + output
+	cut := len(output) - incompleteRune(output)
+	output, l.partial = output[:cut], output[cut:]
+	l.mu.Unlock()
+
+	if output != "" {
+		l.recorder.write(Event{Source: l.source, Type: EventOutput, Output: output})
+	}
+}
+
+func (l *listener) OnRequestRestart() {
+}
+
+func (l *listener) OnRequestStop() {
+}
+
+func (l *listener) OnFilesChanged(changes mediator.ChangeSet) {
+	l.recorder.write(Event{Source: l.source, Type: EventFilesChanged, Changes: &changes})
+}
+
+func (l *listener) OnRestartScheduled(restart mediator.ScheduledRestart) {
+	l.recorder.write(Event{Source: l.source, Type: EventRestart, Restart: &restart})
+}
+
+func (l *listener) OnCancelRestart() {
+	l.recorder.write(Event{Source: l.source, Type: EventCancelRestart})
+}
@@ -0,0 +1,284 @@
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+// Maximum size of a line of a file
+const maxLineSize = 64 * 1024 * 1024
+
+// Interval between the status updates of a replay
+const statusInterval = 250 * time.Millisecond
+
+// A recorded session
+type Session struct {
+	Header
+	Events []Event
+}
+
+// Reads the session recorded in the file at path
+func Load(path string) (*Session, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	session := &Session{}
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: empty recording", path)
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &session.Header); err != nil {
+		return nil, fmt.Errorf("%s: not a recording: %w", path, err)
+	}
+	if session.Version != formatVersion {
+		return nil, fmt.Errorf("%s: unsupported version %d", path, session.Version)
+	}
+
+	for lineNr := 2; scanner.Scan(); lineNr++ {
+		event := Event{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNr, err)
+		}
+		session.Events = append(session.Events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// How long the session lasted, up to its last event
+func (s *Session) Duration() time.Duration {
+	if len(s.Events) == 0 {
+		return 0
+	}
+
+	return s.Events[len(s.Events)-1].Time
+}
+
+// Parses a speed, like "4x", "0.5x" or "2"
+func ParseSpeed(value string) (float64, error) {
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+	if err != nil || speed <= 0 {
+		return 0, fmt.Errorf("invalid speed %q, expected a positive number like 4x", value)
+	}
+
+	return speed, nil
+}
+
+// How far a replay went
+type Status struct {
+	Position time.Duration // time of the session replayed so far
+	Duration time.Duration // of the whole session
+	Speed    float64
+	Paused   bool
+	Ended    bool // whether every event was replayed
+}
+
+// Replays a session: sends its events to the mediators of its sources,
+// as they happened, faster or slower
+type Player struct {
+	session   *Session
+	sources   []Source
+	mediators map[string]mediator.Mediator
+	speed     float64
+	reset     func()       // forgets what the events sent so far displayed
+	onStatus  func(Status) // told how far the replay went
+	mu        sync.Mutex
+	next      int           // index of the next event to send
+	clock     time.Duration // time of the session replayed at anchor
+	anchor    time.Time
+	paused    bool
+	wake      chan struct{} // the next event changed
+}
+
+func NewPlayer(session *Session, speed float64) *Player {
+	p := &Player{
+		session:   session,
+		mediators: make(map[string]mediator.Mediator),
+		speed:     speed,
+		reset:     func() {},
+		onStatus:  func(Status) {},
+		anchor:    time.Now(),
+		wake:      make(chan struct{}, 1),
+	}
+
+	for _, source := range session.Sources {
+		source.Mediator = mediator.NewMediator()
+		p.mediators[source.Name] = source.Mediator
+		p.sources = append(p.sources, source)
+	}
+
+	return p
+}
+
+// The sources of the session, whose mediators the events are sent to
+func (p *Player) Sources() []Source {
+	return p.sources
+}
+
+// Sets what forgets what was displayed, before the session is replayed
+// again from its start
+func (p *Player) SetResetListener(reset func()) {
+	p.reset = reset
+}
+
+// Sets what is told how far the replay went, as it goes
+func (p *Player) SetStatusListener(listener func(Status)) {
+	p.onStatus = listener
+}
+
+// Replays the session, forever: seeking may replay it again
+func (p *Player) Play() {
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	for {
+		p.mu.Lock()
+		var due <-chan time.Time
+		if !p.paused && p.next < len(p.session.Events) {
+			wait := p.session.Events[p.next].Time - p.position()
+			due = time.After(time.Duration(float64(wait) / p.speed))
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-due:
+			p.mu.Lock()
+			p.sendDue()
+			p.mu.Unlock()
+		case <-ticker.C:
+			p.mu.Lock()
+			p.sendStatus()
+			p.mu.Unlock()
+		case <-p.wake:
+		}
+	}
+}
+
+// Pauses the replay, or resumes it
+func (p *Player) TogglePause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clock, p.anchor = p.position(), time.Now()
+	p.paused = !p.paused
+	p.sendStatus()
+	p.wakeUp()
+}
+
+// Moves the replay forward by offset, or backward when negative,
+// replaying the session again from its start then
+func (p *Player) Seek(offset time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	position := p.position()
+	target := min(max(position+offset, 0), p.session.Duration())
+	if target < position {
+		p.reset()
+		p.next = 0
+	}
+
+	p.clock, p.anchor = target, time.Now()
+	p.sendDue()
+	p.sendStatus()
+	p.wakeUp()
+}
+
+// Time of the session replayed so far
+func (p *Player) position() time.Duration {
+	position := p.clock
+	if !p.paused {
+		position += time.Duration(float64(time.Since(p.anchor)) * p.speed)
+	}
+
+	return min(position, p.session.Duration())
+}
+
+// Sends the events up to the position
+func (p *Player) sendDue() {
+	position := p.position()
+	for p.next < len(p.session.Events) && p.session.Events[p.next].Time <= position {
+		p.send(p.session.Events[p.next])
+		p.next++
+	}
+}
+
+func (p *Player) sendStatus() {
+	p.onStatus(Status{
+		Position: p.position(),
+		Duration: p.session.Duration(),
+		Speed:    p.speed,
+		Paused:   p.paused,
+		Ended:    p.next == len(p.session.Events),
+	})
+}
+
+func (p *Player) wakeUp() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Sends an event to the mediator of its source
+func (p *Player) send(event Event) {
+	m, ok := p.mediators[event.Source]
+	if !ok {
+		return
+	}
+
+	switch event.Type {
+	case EventStart:
+		m.SendStart(event.Command)
+	case EventError:
+		m.SendError(errors.New(event.Error))
+	case EventKill:
+		m.SendKill()
+	case EventStop:
+		m.SendStop()
+	case EventExit:
+		if event.Status != nil {
+			m.SendExit(*event.Status)
+		}
+	case EventStep:
+		if event.Step != nil {
+			m.SendStep(*event.Step)
+		}
+	case EventOutput:
+		m.SendOutput(event.Output)
+	case EventFilesChanged:
+		if event.Changes != nil {
+			m.SendFilesChanged(*event.Changes)
+		}
+	case EventRestart:
+		if event.Restart != nil {
+			// the delay left then, at the speed of the replay
+			restart := *event.Restart
+			left := restart.At.Sub(p.session.Started.Add(event.Time))
+			restart.At = time.Now().Add(time.Duration(float64(left) / p.speed))
+			m.SendRestartScheduled(restart)
+		}
+	case EventCancelRestart:
+		m.SendCancelRestart()
+	}
+}
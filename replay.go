@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gaelph/monique/record"
+	"github.com/gaelph/monique/viewport"
+)
+
+// Runs `monique replay`, replaying a recorded session in the user interface.
+// Returns the exit code
+func runReplay(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	speedFlag := flags.String("speed", "1x", "speed of the replay, like 4x or 0.5x")
+	keep := flags.Bool("keep", false, "keep the output of every run, each under a header, instead of clearing it on restart")
+	flags.Usage = func() {
+		printReplayHelp()
+		flags.PrintDefaults()
+	}
+
+	// the options may follow the file
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	path := flags.Arg(0)
+	if err := flags.Parse(flags.Args()[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	speed, err := record.ParseSpeed(*speedFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	session, err := record.Load(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	player := record.NewPlayer(session, speed)
+
+	sources := []viewport.Source{}
+	for _, source := range player.Sources() {
+		sources = append(sources, viewport.Source{
+			Name:     source.Name,
+			Command:  source.Command,
+			Mediator: source.Mediator,
+			Input:    source.Input,
+		})
+	}
+	if len(sources) == 0 {
+		fmt.Fprintf(os.Stderr, "%s: no command was recorded\n", path)
+		return 1
+	}
+
+	options := viewport.DefaultOptions()
+	options.Keep = *keep
+	options.Replay = player
+
+	program := viewport.NewProgram(options, sources...)
+	player.SetResetListener(program.Reset)
+	player.SetStatusListener(func(status record.Status) {
+		program.SetReplayStatus(status.Position, status.Duration, status.Speed, status.Paused, status.Ended)
+	})
+
+	go player.Play()
+	program.Run()
+
+	return 0
+}
+
+func printReplayHelp() {
+	fmt.Fprint(os.Stderr, `monique replay - replay a session recorded with -record
+
+Usage:  monique replay <file> [-speed <speed>] [-keep]
+
+The output is filtered and searched like that of commands, the replay being
+paused with [p], and moved backward or forward with [left] and [right].
+
+Examples:
+  - Replay a session four times faster:
+    $ monique replay flaky.monique -speed 4x
+
+Options:
+`)
+}
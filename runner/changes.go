@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+// Argument replaced with the paths of the files that changed
+const changedPlaceholder = "{changed}"
+
+// A command that is only {changed}, run without changes, like on the first run
+var errEmptyCommand = errors.New("nothing to run, as no file changed for {changed}")
+
+// Replaces {changed} in the arguments of command with the changed files:
+// an argument that is only {changed} becomes one argument per file,
+// otherwise the files are joined with spaces.
+// Without changes, like on the first run, {changed} is replaced with nothing
+func expandChanges(command []string, changes mediator.ChangeSet) []string {
+	expanded := make([]string, 0, len(command))
+	for _, arg := range command {
+		switch {
+		case arg == changedPlaceholder:
+			expanded = append(expanded, changes.Files...)
+		case strings.Contains(arg, changedPlaceholder):
+			expanded = append(expanded, strings.ReplaceAll(arg, changedPlaceholder, strings.Join(changes.Files, " ")))
+		default:
+			expanded = append(expanded, arg)
+		}
+	}
+
+	return expanded
+}
+
+// Environment variables describing the changes, as KEY=value
+func changesEnv(changes mediator.ChangeSet) []string {
+	return []string{
+		"MONIQUE_CHANGED_FILES=" + strings.Join(changes.Files, string(os.PathListSeparator)),
+		"MONIQUE_CHANGE_KIND=" + strings.Join(changes.Kinds, ","),
+	}
+}
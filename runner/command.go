@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Splits a command line into its arguments, the way a shell would
+// for simple cases: arguments are separated by spaces, and can be
+// quoted with single or double quotes, or escaped with a backslash
+func ParseCommand(line string) ([]string, error) {
+	args := []string{}
+	current := strings.Builder{}
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash in %q", line)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	return args, nil
+}
@@ -0,0 +1,27 @@
+package runner
+
+import (
+	"io"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+// Forwards what is read from input, like the standard input, as output,
+// until the end of it, which is reported with SendStop
+func ReadInput(input io.Reader, m mediator.Mediator) {
+	for {
+		bytes := make([]byte, 4096)
+		n, err := input.Read(bytes)
+		if n > 0 {
+			m.SendOutput(string(bytes[:n]))
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				m.SendError(err)
+			}
+			m.SendStop()
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+// Sets commands to run in order before the command, each time it is (re)started.
+// The command is only (re)started if all of them succeed.
+func (r *Runner) SetPreSteps(steps [][]string) {
+	r.preSteps = steps
+}
+
+// Runs the pre-steps in order, stopping at the first one that fails,
+// with {changed} replaced by the files of changes.
+// Returns whether they all succeeded, and were not superseded by a newer
+// start of the pipeline
+func (r *Runner) runPreSteps(changes mediator.ChangeSet, env []string) bool {
+	r.mu.Lock()
+	r.generation += 1
+	generation := r.generation
+	previous := r.building
+	r.mu.Unlock()
+
+	// a newer change makes the running pre-step pointless
+	if previous != nil {
+		if err := previous.terminate(r.grace); err != nil {
+			log.Printf("ERROR: %s\n", err)
+		}
+	}
+
+	for i, step := range r.preSteps {
+		if !r.isCurrentGeneration(generation) {
+			return false
+		}
+
+		step = expandChanges(step, changes)
+		r.sendStep(i, step)
+
+		proc, err := startProcess(step, env)
+		if err != nil {
+			if r.mediator != nil {
+				r.mediator.SendError(err)
+			}
+			return false
+		}
+
+		r.mu.Lock()
+		r.building = proc
+		r.mu.Unlock()
+
+		status := r.follow(proc, step)
+
+		r.mu.Lock()
+		if r.building == proc {
+			r.building = nil
+		}
+		r.mu.Unlock()
+
+		// pre-steps are not supposed to leave anything behind
+		if err := proc.terminate(r.grace); err != nil {
+			log.Printf("ERROR: %s\n", err)
+		}
+
+		if !status.Success() {
+			// the previous run of the command goes on, so the step
+			// is not reported as its exit
+			if r.mediator != nil && r.isCurrentGeneration(generation) {
+				r.mediator.SendError(&mediator.StepError{
+					Step: mediator.Step{
+						Index:   i + 1,
+						Total:   len(r.preSteps) + 1,
+						Command: strings.Join(step, " "),
+					},
+					Status: status,
+				})
+			}
+			return false
+		}
+	}
+
+	return r.isCurrentGeneration(generation)
+}
+
+func (r *Runner) isCurrentGeneration(generation int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.generation == generation
+}
+
+// Reports the start of the step at index, the command being the last step
+func (r *Runner) sendStep(index int, command []string) {
+	if r.mediator == nil {
+		return
+	}
+
+	r.mediator.SendStep(mediator.Step{
+		Index:   index + 1,
+		Total:   len(r.preSteps) + 1,
+		Command: strings.Join(command, " "),
+	})
+}
@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+// Tells whether a command should be restarted after it exited on its own
+type RestartPolicy int8
+
+const (
+	RestartNever     RestartPolicy = 0
+	RestartOnFailure RestartPolicy = 1
+	RestartAlways    RestartPolicy = 2
+)
+
+func ParseRestartPolicy(value string) (RestartPolicy, error) {
+	switch value {
+	case "never", "":
+		return RestartNever, nil
+	case "on-failure":
+		return RestartOnFailure, nil
+	case "always":
+		return RestartAlways, nil
+	}
+
+	return RestartNever, fmt.Errorf(
+		"invalid restart policy %q, expected one of never, on-failure, always",
+		value,
+	)
+}
+
+func (p RestartPolicy) String() string {
+	switch p {
+	case RestartOnFailure:
+		return "on-failure"
+	case RestartAlways:
+		return "always"
+	}
+
+	return "never"
+}
+
+func (p RestartPolicy) shouldRestart(status mediator.ExitStatus) bool {
+	switch p {
+	case RestartOnFailure:
+		return !status.Success()
+	case RestartAlways:
+		return true
+	}
+
+	return false
+}
+
+// Exponential backoff between automatic restarts,
+// doubling from Min up to Max
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func DefaultBackoff() Backoff {
+	return Backoff{
+		Min: 500 * time.Millisecond,
+		Max: 30 * time.Second,
+	}
+}
+
+// Parses a backoff range like "500ms..30s"
+func ParseBackoff(value string) (Backoff, error) {
+	min, max, found := strings.Cut(value, "..")
+	if !found {
+		max = min
+	}
+
+	b := Backoff{}
+	var err error
+	if b.Min, err = time.ParseDuration(strings.TrimSpace(min)); err != nil {
+		return b, err
+	}
+	if b.Max, err = time.ParseDuration(strings.TrimSpace(max)); err != nil {
+		return b, err
+	}
+	if b.Min > b.Max {
+		return b, fmt.Errorf("invalid backoff %q: %s is greater than %s", value, b.Min, b.Max)
+	}
+
+	return b, nil
+}
+
+// Delay before the nth restart attempt (starting at 1)
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.Min
+	for i := 1; i < attempt && delay < b.Max; i++ {
+		delay *= 2
+	}
+
+	if delay > b.Max {
+		return b.Max
+	}
+	return delay
+}
+
+// MARK: - flag.Value
+
+func (b *Backoff) String() string {
+	return fmt.Sprintf("%s..%s", b.Min, b.Max)
+}
+
+func (b *Backoff) Set(value string) error {
+	parsed, err := ParseBackoff(value)
+	if err != nil {
+		return err
+	}
+
+	*b = parsed
+	return nil
+}
@@ -0,0 +1,206 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+// interval between two checks for the process group being gone
+const pollInterval = 50 * time.Millisecond
+
+// A running command, attached to a pty, leading its own process group
+type process struct {
+	cmd      *exec.Cmd
+	pty      *os.File
+	pgid     int
+	started  time.Time
+	exited   chan struct{} // closed once cmd.Wait() returned
+	drained  chan struct{} // closed once all the output has been read
+	reported chan struct{} // closed once the exit has been reported
+}
+
+// starts command in a new session, which also makes it the leader of a
+// new process group, so that the whole group can be signaled at once.
+// env is added to the environment of monique
+func startProcess(command []string, env []string) (*process, error) {
+	if len(command) == 0 {
+		return nil, errEmptyCommand
+	}
+
+	cmd := exec.CommandContext(context.Background(), command[0], command[1:]...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	// Setsid implies a new process group (pgid == pid).
+	// Setpgid cannot be combined with it, as a session leader
+	// is not allowed to change its process group.
+	t, err := pty.StartWithAttrs(cmd, nil, &syscall.SysProcAttr{
+		Setsid:  true,
+		Setctty: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &process{
+		cmd:      cmd,
+		pty:      t,
+		pgid:     cmd.Process.Pid,
+		started:  time.Now(),
+		exited:   make(chan struct{}),
+		drained:  make(chan struct{}),
+		reported: make(chan struct{}),
+	}
+
+	return p, nil
+}
+
+// Waits for the process to exit, and describes how it did
+func (p *process) wait(command string) mediator.ExitStatus {
+	p.cmd.Wait()
+	close(p.exited)
+
+	status := mediator.ExitStatus{
+		Command:  command,
+		Duration: time.Since(p.started),
+	}
+
+	state := p.cmd.ProcessState
+	if state == nil {
+		status.Code = -1
+		return status
+	}
+
+	status.Code = state.ExitCode()
+	status.UserTime = state.UserTime()
+	status.SystemTime = state.SystemTime()
+
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		status.Signal = ws.Signal()
+	}
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		status.MaxRSS = maxRSS(rusage)
+	}
+
+	return status
+}
+
+// sends sig to every process in the group
+func (p *process) signal(sig syscall.Signal) error {
+	return syscall.Kill(-p.pgid, sig)
+}
+
+// whether any process of the group is still alive
+func (p *process) groupAlive() bool {
+	err := syscall.Kill(-p.pgid, 0)
+	if err != nil {
+		return errors.Is(err, syscall.EPERM)
+	}
+
+	// zombies still count as members of the group, and orphans are only
+	// reaped when the init process gets to it (if ever, in containers)
+	if alive, ok := groupHasLiveMembers(p.pgid); ok {
+		return alive
+	}
+
+	return true
+}
+
+// Scans /proc for non-zombie processes in the process group.
+// ok is false when /proc is not available
+func groupHasLiveMembers(pgid int) (alive bool, ok bool) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return false, false
+	}
+
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+
+		stat, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+
+		// pid (comm) state ppid pgrp ...
+		// comm may contain spaces and parenthesis
+		end := strings.LastIndexByte(string(stat), ')')
+		if end < 0 {
+			continue
+		}
+		fields := strings.Fields(string(stat[end+1:]))
+		if len(fields) < 3 {
+			continue
+		}
+
+		if fields[2] == strconv.Itoa(pgid) && fields[0] != "Z" {
+			return true, true
+		}
+	}
+
+	return false, true
+}
+
+// whether the leader has been waited for and the rest of the group is gone
+func (p *process) gone() bool {
+	select {
+	case <-p.exited:
+		return !p.groupAlive()
+	default:
+		return false
+	}
+}
+
+// Sends SIGTERM to the process group, waits up to grace for it to be gone,
+// then escalates to SIGKILL.
+// Returns once the whole group has exited, or an error if some of it
+// outlives SIGKILL
+func (p *process) terminate(grace time.Duration) error {
+	if p.gone() {
+		return nil
+	}
+
+	p.signal(syscall.SIGTERM)
+	if p.waitGone(grace) {
+		return nil
+	}
+
+	log.Printf("Process group %d still alive after %s, sending SIGKILL\n", p.pgid, grace)
+	p.signal(syscall.SIGKILL)
+	// SIGKILL cannot be caught, but the kernel may take a moment
+	if !p.waitGone(time.Second) {
+		return fmt.Errorf("process group %d still has members after SIGKILL", p.pgid)
+	}
+
+	return nil
+}
+
+// polls until the group is gone or the timeout expires
+func (p *process) waitGone(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if p.gone() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(pollInterval)
+	}
+}
@@ -1,33 +1,52 @@
 package runner
 
 import (
-	"context"
+	"fmt"
 	"log"
-	"os/exec"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 
-	"github.com/creack/pty"
-
 	"github.com/gaelph/monique/mediator"
 )
 
+// Default time given to a process group to exit before being killed
+const DefaultGracePeriod = 5 * time.Second
+
+// How long to wait for the remaining output once the command exited
+const drainTimeout = 200 * time.Millisecond
+
 type Runner struct {
 	mediator         mediator.Mediator
-	control          chan bool
+	current          *process // the last started process, until stopped
 	debouncedRestart func()
 	Command          []string
 	delay            int
+	grace            time.Duration      // time given to the process group to exit after SIGTERM
+	policy           RestartPolicy      // whether to restart the command when it exits on its own
+	backoff          Backoff            // delay between automatic restarts
+	maxRestarts      int                // maximum consecutive automatic restarts, 0 for unlimited
+	attempts         int                // consecutive automatic restarts so far
+	pendingRestart   *time.Timer        // automatic restart waiting for its delay to expire
+	preSteps         [][]string         // commands that must succeed before the command is (re)started
+	building         *process           // the pre-step currently running, if any
+	generation       int                // incremented each time the pipeline starts over
+	env              []string           // environment variables added for the command, as KEY=value
+	changes          mediator.ChangeSet // files changed since the command was last started
+	active           int                // runs going on, and restarts about to start one
+	idle             *sync.Cond         // signaled when there is no active run anymore
+	mu               sync.Mutex
 }
 
 func NewRunner(command []string, delay int) *Runner {
 	r := &Runner{
 		Command: command,
-		control: make(chan bool),
 		delay:   delay,
+		grace:   DefaultGracePeriod,
+		backoff: DefaultBackoff(),
 	}
 
+	r.idle = sync.NewCond(&r.mu)
 	r.debouncedRestart = debounce(func() {
 		r.restart()
 	}, 150)
@@ -40,18 +59,71 @@ func (r *Runner) SetMediator(mediator mediator.Mediator) {
 	r.mediator.AddListener(r)
 }
 
+// Sets how long the process group is given to exit after SIGTERM,
+// before being sent SIGKILL
+func (r *Runner) SetGracePeriod(grace time.Duration) {
+	r.grace = grace
+}
+
+// Sets environment variables, as KEY=value, added to the environment
+// of the command and its pre-steps
+func (r *Runner) SetEnv(env []string) {
+	r.env = env
+}
+
+// Sets when the command should be restarted after it exited on its own,
+// how long to wait between attempts, and how many consecutive attempts
+// to make (0 for unlimited)
+func (r *Runner) SetRestartPolicy(policy RestartPolicy, backoff Backoff, maxRestarts int) {
+	r.policy = policy
+	r.backoff = backoff
+	r.maxRestarts = maxRestarts
+}
+
+// Runs the pre-steps, if any, then starts the command and blocks until it exits.
+// When a pre-step fails, the previous run of the command is left running.
 func (r *Runner) Start() {
+	r.begin()
+	defer r.end()
+
 	log.Println("Starting process")
 	time.Sleep(time.Duration(r.delay) * time.Millisecond)
 
+	r.mu.Lock()
+	changes := r.changes
+	r.changes = mediator.ChangeSet{}
+	r.mu.Unlock()
+
+	command := expandChanges(r.Command, changes)
+	env := append(changesEnv(changes), r.env...)
+
+	// the previous run, if any, is left running
+	if len(command) == 0 {
+		if r.mediator != nil {
+			r.mediator.SendError(errEmptyCommand)
+		}
+		return
+	}
+
+	// the run begins with its pre-steps, whose output is part of it, while
+	// the previous run goes on until they succeeded
 	if r.mediator != nil {
-		r.mediator.SendStart(strings.Join(r.Command, " "))
+		r.mediator.SendStart(strings.Join(command, " "))
+		if !changes.Empty() {
+			r.mediator.SendOutput(changes.String() + "\n")
+		}
+	}
+
+	if len(r.preSteps) > 0 {
+		if !r.runPreSteps(changes, env) {
+			return
+		}
+
+		r.Stop()
+		r.sendStep(len(r.preSteps), command)
 	}
 
-	cmdName := r.Command[0]
-	cmdArgs := r.Command[1:]
-	cmd := exec.CommandContext(context.Background(), cmdName, cmdArgs...)
-	t, err := pty.Start(cmd)
+	proc, err := startProcess(command, env)
 	if err != nil {
 		if r.mediator != nil {
 			r.mediator.SendError(err)
@@ -59,40 +131,185 @@ func (r *Runner) Start() {
 		return
 	}
 
-	go func(r *Runner) {
-		for {
-			bytes := make([]byte, 1024)
-			n, err := t.Read(bytes)
-			// r.Output <- string(bytes[:n])
-			outStr := string(bytes[:n])
-			log.Printf("🚀  ~ e/r/runner.go:67 ~ outStr: %+v\n", outStr)
-			r.mediator.SendOutput(outStr)
-
-			if err != nil {
-				if r.mediator != nil {
-					r.mediator.SendStop()
-				}
-				return
-			}
+	r.mu.Lock()
+	r.current = proc
+	r.mu.Unlock()
+
+	status := r.follow(proc, command)
+	if r.mediator != nil {
+		r.mediator.SendExit(status)
+	}
+	close(proc.reported)
+
+	r.mu.Lock()
+	exitedOnItsOwn := r.current == proc
+	r.mu.Unlock()
+
+	if exitedOnItsOwn {
+		r.scheduleRestart(status)
+	}
+}
 
+// Applies the restart policy after the command exited on its own
+func (r *Runner) scheduleRestart(status mediator.ExitStatus) {
+	restart, scheduled := r.armRestart(status)
+
+	if r.mediator == nil {
+		return
+	}
+
+	if scheduled {
+		r.mediator.SendRestartScheduled(restart)
+	} else if restart.Attempt > 0 {
+		r.mediator.SendOutput(fmt.Sprintf("Giving up after %d restarts\n", restart.Attempt))
+	}
+}
+
+// Starts the timer for the next automatic restart if the policy says so.
+// When giving up, the returned restart holds the number of attempts made
+func (r *Runner) armRestart(
+	status mediator.ExitStatus,
+) (restart mediator.ScheduledRestart, scheduled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.policy.shouldRestart(status) {
+		r.attempts = 0
+		return restart, false
+	}
+
+	// a run that lasted longer than the longest delay is considered healthy
+	if status.Duration >= r.backoff.Max {
+		r.attempts = 0
+	}
+
+	if r.maxRestarts > 0 && r.attempts >= r.maxRestarts {
+		log.Printf("Giving up after %d restarts\n", r.attempts)
+		restart.Attempt = r.attempts
+		return restart, false
+	}
+
+	r.attempts += 1
+	delay := r.backoff.Delay(r.attempts)
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		r.mu.Lock()
+		if r.pendingRestart != timer {
+			r.mu.Unlock()
+			return
 		}
-	}(r)
+		r.pendingRestart = nil
+		r.mu.Unlock()
 
-	defer func() {
-		t.Close()
-	}()
+		r.Stop()
+		r.Start()
+	})
+	r.pendingRestart = timer
 
-	<-r.control
-	cmd.Process.Signal(syscall.SIGTERM)
-	if r.mediator != nil {
-		r.mediator.SendKill()
+	return mediator.ScheduledRestart{
+		At:          time.Now().Add(delay),
+		Attempt:     r.attempts,
+		MaxAttempts: r.maxRestarts,
+	}, true
+}
+
+// Cancels the pending automatic restart, if any.
+// Returns whether there was one
+func (r *Runner) cancelRestart() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pendingRestart == nil {
+		return false
 	}
+
+	r.pendingRestart.Stop()
+	r.pendingRestart = nil
+
+	return true
+}
+
+// Forwards the output of the process, and waits for it to exit
+func (r *Runner) follow(proc *process, command []string) mediator.ExitStatus {
+	go r.readOutput(proc)
+
+	status := proc.wait(strings.Join(command, " "))
+
+	// let the last bytes of output through before the exit is reported,
+	// unless some orphan keeps the pty open
+	select {
+	case <-proc.drained:
+	case <-time.After(drainTimeout):
+	}
+
+	return status
 }
 
+// forwards the process output to the mediator, until the pty is closed
+func (r *Runner) readOutput(proc *process) {
+	defer close(proc.drained)
+	defer proc.pty.Close()
+
+	for {
+		bytes := make([]byte, 1024)
+		n, err := proc.pty.Read(bytes)
+		if n > 0 && r.mediator != nil {
+			r.mediator.SendOutput(string(bytes[:n]))
+		}
+
+		if err != nil {
+			if r.mediator != nil {
+				r.mediator.SendStop()
+			}
+			return
+		}
+	}
+}
+
+// Terminates the process group of the running command, if any,
+// and returns once all of its processes are gone
 func (r *Runner) Stop() {
+	// the countdown of the pending restart is not shown anymore
+	if r.cancelRestart() && r.mediator != nil {
+		r.mediator.SendCancelRestart()
+	}
+
+	r.mu.Lock()
+	proc := r.current
+	r.current = nil
+	building := r.building
+	r.mu.Unlock()
+
+	if building != nil {
+		if err := building.terminate(r.grace); err != nil {
+			log.Printf("ERROR: %s\n", err)
+		}
+	}
+
+	if proc == nil || proc.gone() {
+		return
+	}
+
 	log.Println("Killing process")
-	go r.mediator.SendOutput("Killing process\n")
-	r.control <- true
+	if r.mediator != nil {
+		go r.mediator.SendOutput("Killing process\n")
+	}
+
+	err := proc.terminate(r.grace)
+	<-proc.reported
+
+	if err != nil {
+		log.Printf("ERROR: %s\n", err)
+		if r.mediator != nil {
+			r.mediator.SendError(err)
+		}
+		return
+	}
+
+	if r.mediator != nil {
+		r.mediator.SendKill()
+	}
 }
 
 func (r *Runner) Restart() {
@@ -100,9 +317,48 @@ func (r *Runner) Restart() {
 }
 
 func (r *Runner) restart() {
-	r.Stop()
-	time.Sleep(time.Duration(100) * time.Millisecond)
-	go r.Start()
+	// restarts from the user are not part of a series of failures
+	r.mu.Lock()
+	r.attempts = 0
+	r.mu.Unlock()
+
+	// the run being stopped does not leave the runner idle
+	r.begin()
+
+	// with pre-steps, the previous run is only stopped once they succeed
+	if len(r.preSteps) == 0 {
+		r.Stop()
+		time.Sleep(time.Duration(100) * time.Millisecond)
+	}
+	go func() {
+		defer r.end()
+		r.Start()
+	}()
+}
+
+func (r *Runner) begin() {
+	r.mu.Lock()
+	r.active++
+	r.mu.Unlock()
+}
+
+func (r *Runner) end() {
+	r.mu.Lock()
+	r.active--
+	if r.active == 0 {
+		r.idle.Broadcast()
+	}
+	r.mu.Unlock()
+}
+
+// Blocks until the command is not running, nor being restarted
+func (r *Runner) Wait() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for r.active > 0 {
+		r.idle.Wait()
+	}
 }
 
 // a debounce function and struct
@@ -132,9 +388,39 @@ func (runner *Runner) OnKill() {
 func (runner *Runner) OnStop() {
 }
 
+func (runner *Runner) OnExit(status mediator.ExitStatus) {
+}
+
+func (runner *Runner) OnStep(step mediator.Step) {
+}
+
 func (runner *Runner) OnOutput(output string) {
 }
 
 func (runner *Runner) OnRequestRestart() {
 	runner.debouncedRestart()
 }
+
+func (runner *Runner) OnRequestStop() {
+	// Stop blocks until the command is gone
+	go runner.Stop()
+}
+
+func (runner *Runner) OnFilesChanged(changes mediator.ChangeSet) {
+	runner.mu.Lock()
+	runner.changes.Merge(changes)
+	runner.mu.Unlock()
+
+	runner.debouncedRestart()
+}
+
+func (runner *Runner) OnRestartScheduled(restart mediator.ScheduledRestart) {
+}
+
+func (runner *Runner) OnCancelRestart() {
+	if runner.cancelRestart() {
+		runner.mu.Lock()
+		runner.attempts = 0
+		runner.mu.Unlock()
+	}
+}
@@ -0,0 +1,8 @@
+package runner
+
+import "syscall"
+
+// macOS reports ru_maxrss in bytes
+func maxRSS(rusage *syscall.Rusage) int64 {
+	return rusage.Maxrss / 1024
+}
@@ -0,0 +1,8 @@
+package runner
+
+import "syscall"
+
+// Linux reports ru_maxrss in kilobytes
+func maxRSS(rusage *syscall.Rusage) int64 {
+	return rusage.Maxrss
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package runner
+
+import "syscall"
+
+// BSDs report ru_maxrss in kilobytes
+func maxRSS(rusage *syscall.Rusage) int64 {
+	return int64(rusage.Maxrss)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gaelph/monique/config"
+	"github.com/gaelph/monique/runner"
+	"github.com/gaelph/monique/viewport"
+)
+
+// Loads the configuration file given with -config, or found in the working
+// directory, and uses its values for what was not given on the command line
+func (s *settings) loadConfig(profile string, command *[]string) error {
+	path := s.configPath
+	if path == "" {
+		path = config.Find(".")
+	}
+	if path == "" {
+		if profile != "" {
+			return fmt.Errorf("no configuration file found for profile @%s", profile)
+		}
+		return nil
+	}
+
+	cfg, err := config.Load(path, profile)
+	if err != nil {
+		return err
+	}
+
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	isGiven := func(names ...string) bool {
+		for _, name := range names {
+			if given[name] {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !isGiven("watch", "w") && len(cfg.Watch) > 0 {
+		s.watchList = cfg.Watch
+	}
+	if !isGiven("exts", "e") && len(cfg.Exts) > 0 {
+		s.exts = strings.Join(cfg.Exts, ",")
+	}
+	if !isGiven("include") && len(cfg.Include) > 0 {
+		s.includes = cfg.Include
+	}
+	if !isGiven("ignore") && len(cfg.Ignore) > 0 {
+		s.ignores = cfg.Ignore
+	}
+	if !isGiven("no-ignore") && cfg.NoIgnore != nil {
+		s.noIgnore = *cfg.NoIgnore
+	}
+	if !isGiven("poll") && cfg.Poll != "" {
+		if s.poll, err = time.ParseDuration(cfg.Poll); err != nil {
+			return fmt.Errorf("%s: poll: %w", path, err)
+		}
+	}
+	if !isGiven("no-hash") && cfg.NoHash != nil {
+		s.noHash = *cfg.NoHash
+	}
+	if !isGiven("delay", "d") && cfg.Delay != nil {
+		s.delay = *cfg.Delay
+	}
+	if !isGiven("grace") && cfg.Grace != "" {
+		if s.grace, err = time.ParseDuration(cfg.Grace); err != nil {
+			return fmt.Errorf("%s: grace: %w", path, err)
+		}
+	}
+	if !isGiven("restart") && cfg.Restart != "" {
+		s.restart = cfg.Restart
+	}
+	if !isGiven("max-restarts") && cfg.MaxRestarts != nil {
+		s.maxRestarts = *cfg.MaxRestarts
+	}
+	if !isGiven("backoff") && cfg.Backoff != "" {
+		if s.backoff, err = runner.ParseBackoff(cfg.Backoff); err != nil {
+			return fmt.Errorf("%s: backoff: %w", path, err)
+		}
+	}
+
+	if !isGiven("cmd") {
+		for _, name := range cfg.CommandNames() {
+			c, err := parseConfigCommand(name, cfg.Commands[name])
+			if err != nil {
+				return fmt.Errorf("%s: commands.%s: %w", path, name, err)
+			}
+			s.commands = append(s.commands, c)
+		}
+	}
+
+	// the pre-steps and environment of the configuration file belong
+	// to its command, unless -pre is given
+	if len(*command) == 0 && cfg.Command.Command != "" {
+		c, err := parseConfigCommand("", cfg.Command)
+		if err != nil {
+			return fmt.Errorf("%s: command: %w", path, err)
+		}
+
+		*command = c.command
+		s.env = c.env
+		if !isGiven("pre") {
+			s.steps = c.steps
+		}
+	}
+
+	for action, keys := range cfg.Keys {
+		if err := s.options.KeyMap.Rebind(action, splitList(keys)...); err != nil {
+			return fmt.Errorf("%s: keys: %w", path, err)
+		}
+	}
+	if !isGiven("scrollback") && cfg.Scrollback != nil {
+		s.options.Scrollback = *cfg.Scrollback
+	}
+	if !isGiven("structured") && cfg.Structured != nil {
+		s.options.Structured = *cfg.Structured
+	}
+	if !isGiven("errorfile") && cfg.ErrorFile != "" {
+		s.options.ErrorFile = cfg.ErrorFile
+	}
+	if !isGiven("keep") && cfg.Keep != nil {
+		s.options.Keep = *cfg.Keep
+	}
+	if !isGiven("history") && cfg.History != nil {
+		s.options.History = *cfg.History
+	}
+	if !isGiven("diff-ignore") && cfg.DiffIgnore != nil {
+		s.diffIgnore = *cfg.DiffIgnore
+	}
+	if !isGiven("control") && cfg.Control != "" {
+		s.control = cfg.Control
+	}
+	if !isGiven("http") && cfg.HTTP != "" {
+		s.http = cfg.HTTP
+	}
+	if err := viewport.SetLevelPatterns(cfg.Levels); err != nil {
+		return fmt.Errorf("%s: levels: %w", path, err)
+	}
+	if len(cfg.Filters) > 0 {
+		s.options.Filters = cfg.Filters
+	}
+
+	viewport.ApplyTheme(viewport.Theme{
+		Title:           cfg.Theme.Title,
+		TitleText:       cfg.Theme.TitleText,
+		Match:           cfg.Theme.Match,
+		MatchText:       cfg.Theme.MatchText,
+		ActiveMatch:     cfg.Theme.ActiveMatch,
+		ActiveMatchText: cfg.Theme.ActiveMatchText,
+	})
+
+	return nil
+}
+
+func parseConfigCommand(name string, c config.Command) (namedCommand, error) {
+	command, err := runner.ParseCommand(c.Command)
+	if err != nil {
+		return namedCommand{}, err
+	}
+
+	steps := make([][]string, len(c.Pre))
+	for i, pre := range c.Pre {
+		if steps[i], err = runner.ParseCommand(pre); err != nil {
+			return namedCommand{}, err
+		}
+	}
+
+	env := make([]string, 0, len(c.Env))
+	for key, value := range c.Env {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+
+	return namedCommand{
+		name:    name,
+		command: command,
+		steps:   steps,
+		env:     env,
+	}, nil
+}
+
+// Splits a comma separated list, trimming spaces
+func splitList(list string) []string {
+	items := strings.Split(list, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+
+	return items
+}
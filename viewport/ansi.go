@@ -0,0 +1,197 @@
+package viewport
+
+import (
+	"strings"
+)
+
+const resetSequence = "\x1b[0m"
+
+// An escape sequence, found before the character at pos in the visible text
+type escape struct {
+	pos int
+	seq string
+	sgr bool // whether it sets colors or text attributes
+}
+
+// A line of output, its visible text apart from its escape sequences,
+// so that filters and searches only see what is displayed
+type styledLine struct {
+	raw     string   // the line as received, escape sequences included
+	text    string   // the visible text
+	escapes []escape // the escape sequences, in order
+	prefix  []string // SGR sequences in effect at the start of the line, set by previous lines
+
+	continued bool      // whether the line continues the previous one, wrapped at the width of the viewport
+	record    *record   // for the first line of a structured log line, its fields
+	level     level     // for the first line of an output line, its level
+	location  *location // for the first line of an output line, the source location it reports
+	runHeader bool      // for the first line of an output line, whether it is the header of a run
+}
+
+// Splits a line into its visible text and its escape sequences.
+// prefix holds the SGR sequences in effect before the line
+func parseLine(raw string, prefix []string) styledLine {
+	// lines from the pty end with \r\n, and a reset after the \r would
+	// be written at the start of the line
+	raw = strings.TrimSuffix(raw, "\r")
+
+	line := styledLine{raw: raw, prefix: prefix}
+	if !strings.Contains(raw, "\x1b") {
+		line.text = raw
+		return line
+	}
+
+	text := strings.Builder{}
+	for i := 0; i < len(raw); {
+		if raw[i] != '\x1b' {
+			text.WriteByte(raw[i])
+			i++
+			continue
+		}
+
+		end := escapeEnd(raw, i)
+		seq := raw[i:end]
+		line.escapes = append(line.escapes, escape{
+			pos: text.Len(),
+			seq: seq,
+			sgr: strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m"),
+		})
+		i = end
+	}
+	line.text = text.String()
+
+	return line
+}
+
+// Index of the end of the escape sequence starting at start
+func escapeEnd(s string, start int) int {
+	i := start + 1
+	if i >= len(s) {
+		return i
+	}
+
+	switch s[i] {
+	// Control Sequence Introducer: parameters, intermediates, then a final byte
+	case '[':
+		i++
+		for i < len(s) && (s[i] < 0x40 || s[i] > 0x7e) {
+			i++
+		}
+		return min(i+1, len(s))
+
+	// Operating System Command, like hyperlinks, ended by BEL or ST
+	case ']':
+		for i++; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	}
+
+	return i + 1
+}
+
+// The SGR sequences in effect once seq is applied to state
+func applySGR(state []string, seq string) []string {
+	params := strings.TrimSuffix(strings.TrimPrefix(seq, "\x1b["), "m")
+	switch {
+	case params == "" || params == "0":
+		return nil
+	case strings.HasPrefix(params, "0;"):
+		return []string{seq}
+	}
+
+	return append(state[:len(state):len(state)], seq)
+}
+
+// The SGR sequences in effect at the end of the line
+func (l styledLine) endState() []string {
+	state := l.prefix
+	for _, esc := range l.escapes {
+		if esc.sgr {
+			state = applySGR(state, esc.seq)
+		}
+	}
+
+	return state
+}
+
+// Renders the line with its original colors, the search matches,
+// in order, highlighted on top of them
+func (l styledLine) render(matches []searchMatch, activeMatchID int) string {
+	if len(l.escapes) == 0 && len(l.prefix) == 0 && len(matches) == 0 {
+		return l.raw
+	}
+
+	builder := strings.Builder{}
+	state := l.prefix
+	for _, seq := range state {
+		builder.WriteString(seq)
+	}
+
+	next := 0 // index of the next escape sequence to write
+	writeEscape := func() {
+		esc := l.escapes[next]
+		builder.WriteString(esc.seq)
+		if esc.sgr {
+			state = applySGR(state, esc.seq)
+		}
+		next++
+	}
+	// writes text[from:to], with the escape sequences found along
+	writeText := func(from, to int) {
+		for next < len(l.escapes) && l.escapes[next].pos < to {
+			builder.WriteString(l.text[from:l.escapes[next].pos])
+			from = l.escapes[next].pos
+			writeEscape()
+		}
+		builder.WriteString(l.text[from:to])
+	}
+
+	pos := 0
+	for _, match := range matches {
+		writeText(pos, match.start)
+		for next < len(l.escapes) && l.escapes[next].pos == match.start {
+			writeEscape()
+		}
+
+		style := searchMatchStyle
+		if activeMatchID >= 0 && activeMatchID == match.id {
+			style = activeMatchStyle
+		}
+		builder.WriteString(style.Render(l.text[match.start:match.end]))
+
+		// the colors of the match are only restored after the highlight
+		var deferred []string
+		for next < len(l.escapes) && l.escapes[next].pos < match.end {
+			esc := l.escapes[next]
+			if esc.sgr {
+				state = applySGR(state, esc.seq)
+			} else {
+				deferred = append(deferred, esc.seq)
+			}
+			next++
+		}
+		builder.WriteString(resetSequence)
+		for _, seq := range append(state[:len(state):len(state)], deferred...) {
+			builder.WriteString(seq)
+		}
+
+		pos = match.end
+	}
+	writeText(pos, len(l.text))
+	for next < len(l.escapes) {
+		writeEscape()
+	}
+
+	// lines are displayed on their own, when filtered
+	if len(state) > 0 {
+		builder.WriteString(resetSequence)
+	}
+
+	return builder.String()
+}
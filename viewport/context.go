@@ -0,0 +1,128 @@
+package viewport
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Dims the context lines, kept as their first escape sequence so that
+// it is restored after search highlights
+const dimSequence = "\x1b[2m"
+
+// The numbers of the lines to display, between from and to: the matching
+// lines, and their context lines. matches are sorted, and may start with
+// matches before from, whose context lines after them are displayed
+func (m model) withContext(matches []int, from, to int) []int {
+	if m.contextBefore == 0 && m.contextAfter == 0 {
+		indices := make([]int, 0, len(matches))
+		for _, lineNr := range matches {
+			if lineNr >= from {
+				indices = append(indices, lineNr)
+			}
+		}
+		return indices
+	}
+
+	indices := make([]int, 0, len(matches)*(1+m.contextBefore+m.contextAfter))
+	next := from // first line that may be added
+	for _, lineNr := range matches {
+		start := max(lineNr-m.contextBefore, next)
+		end := min(lineNr+m.contextAfter+1, to)
+		for i := start; i < end; i++ {
+			line := m.allLines.at(i)
+			if m.isHidden(line, m.allLines.at(m.allLines.head(i)).record) {
+				continue
+			}
+			indices = append(indices, i)
+		}
+		next = max(next, end)
+	}
+
+	return indices
+}
+
+func (m model) hasContext() bool {
+	return m.contextBefore > 0 || m.contextAfter > 0
+}
+
+// Whether the line numbered lineNr is only displayed as the context
+// of a matching line
+func (m model) isContextLine(lineNr int) bool {
+	if !m.hasContext() {
+		return false
+	}
+
+	i := sort.SearchInts(m.matchedLines, lineNr)
+	return i == len(m.matchedLines) || m.matchedLines[i] != lineNr
+}
+
+// Grows the context around matching lines by offset lines, before and after
+func (m model) growContext(offset int) model {
+	m.contextBefore = max(m.contextBefore+offset, 0)
+	m.contextAfter = max(m.contextAfter+offset, 0)
+	m.refresh()
+
+	return m
+}
+
+// The line as displayed when it is the context of a matching line:
+// dimmed, without its colors
+func dimmed(line styledLine) styledLine {
+	return parseLine(dimSequence+line.text, nil)
+}
+
+// Indices in filteredIndices of the lines starting a hunk of contiguous
+// lines, but the first one, when context lines are displayed
+func (m model) findHunks() []int {
+	hunks := []int{}
+	if !m.hasContext() {
+		return hunks
+	}
+
+	for i := 1; i < len(m.filteredIndices); i++ {
+		if m.filteredIndices[i] != m.filteredIndices[i-1]+1 {
+			hunks = append(hunks, i)
+		}
+	}
+
+	return hunks
+}
+
+// The row of the viewport where the line at index i of filteredIndices
+// is displayed, after the separators of the hunks before it
+func (m model) rowOf(i int) int {
+	return i + sort.SearchInts(m.hunkStarts, i+1)
+}
+
+// Sets the rendered lines as the content of the viewport,
+// with a separator between hunks
+func (m *model) setViewportContent() {
+	m.hunkStarts = m.findHunks()
+	if len(m.hunkStarts) == 0 {
+		m.viewport.SetRows(m.renderedLines)
+		return
+	}
+
+	separator := contextSeparatorStyle.Render(strings.Repeat("╌", max(m.viewport.Width, 1)))
+	rows := make([]string, 0, len(m.renderedLines)+len(m.hunkStarts))
+	next := 0
+	for i, line := range m.renderedLines {
+		if next < len(m.hunkStarts) && m.hunkStarts[next] == i {
+			rows = append(rows, separator)
+			next++
+		}
+		rows = append(rows, line)
+	}
+
+	m.viewport.SetRows(rows)
+}
+
+// Like "Context: 3" or "Context: 2 before, 5 after"
+func (m model) contextStatus() string {
+	if m.contextBefore == m.contextAfter {
+		return fmt.Sprintf("Context: %d", m.contextBefore)
+	}
+
+	return fmt.Sprintf("Context: %d before, %d after", m.contextBefore, m.contextAfter)
+}
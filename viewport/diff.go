@@ -0,0 +1,302 @@
+package viewport
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Matches of this pattern are ignored when comparing the lines of two runs:
+// timestamps, durations and sizes, which change from one run to the next
+const DefaultDiffIgnore = `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?` +
+	`|\d{2}:\d{2}:\d{2}(\.\d+)?` +
+	`|\b\d+(\.\d+)?(ns|µs|us|ms|s|m|h|KB|MB|GB)\b`
+
+var diffIgnore = regexp.MustCompile(DefaultDiffIgnore)
+
+// Replaces the pattern whose matches are ignored when comparing runs.
+// An empty pattern compares the lines as they are
+func SetDiffIgnore(pattern string) error {
+	if pattern == "" {
+		diffIgnore = nil
+		return nil
+	}
+
+	reg, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("diff-ignore: %w", err)
+	}
+	diffIgnore = reg
+
+	return nil
+}
+
+// Whether a line was kept, removed or added, from one run to the other
+type diffOp int8
+
+const (
+	diffKeep diffOp = iota
+	diffRemove
+	diffAdd
+)
+
+// A line of the comparison of two runs
+type diffLine struct {
+	op  diffOp
+	raw string // the line as received, escape sequences included
+}
+
+// Number of unchanged lines displayed around the changed ones
+const diffContext = 3
+
+// Beyond this number of edits, the lines of the runs are not matched
+// anymore, and the rest of them are all removed then added
+const maxDiffEdits = 2000
+
+// An output line, once its wrapped lines are joined back together
+type outputLine struct {
+	raw string
+	key string // the visible text, without what is ignored when comparing
+}
+
+// The output lines of a run, from the line numbered from to the one
+// numbered to, wrapped lines being joined back together.
+// Run headers, which differ from one run to the next, are left out
+func outputLines(lines *scrollback, from, to int) []outputLine {
+	output := []outputLine{}
+	header := false
+	for n := from; n < to; n++ {
+		line := lines.at(n)
+		if !line.continued {
+			header = line.runHeader
+		}
+		if header {
+			continue
+		}
+		if line.continued && len(output) > 0 {
+			last := &output[len(output)-1]
+			last.raw += line.raw
+			last.key += line.text
+			continue
+		}
+		output = append(output, outputLine{raw: line.raw, key: line.text})
+	}
+	// the line after the last newline is empty until more output comes in
+	if len(output) > 0 && output[len(output)-1].raw == "" {
+		output = output[:len(output)-1]
+	}
+
+	for i := range output {
+		if diffIgnore != nil {
+			output[i].key = diffIgnore.ReplaceAllString(output[i].key, "")
+		}
+		output[i].key = strings.TrimRight(output[i].key, " ")
+	}
+
+	return output
+}
+
+// The shortest edit script turning the lines of a into those of b,
+// with Myers' algorithm
+func diffLines(a, b []outputLine) []diffLine {
+	// the lines they start and end with are kept as they are
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix].key == b[prefix].key {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix].key == b[len(b)-1-suffix].key {
+		suffix++
+	}
+
+	result := make([]diffLine, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		result = append(result, diffLine{op: diffKeep, raw: line.raw})
+	}
+	result = append(result, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		result = append(result, diffLine{op: diffKeep, raw: line.raw})
+	}
+
+	return result
+}
+
+// Myers' O(ND) algorithm: explores the edit graph by increasing number
+// of edits d, keeping the furthest point reached on each diagonal k,
+// then walks back the points of each d to find the edits
+func myers(a, b []outputLine) []diffLine {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	trace := [][]int{}
+
+	found := false
+	for d := 0; d <= n+m && d <= maxDiffEdits && !found; d++ {
+		// the furthest points of d-1 edits, on the diagonals -d+1 to d-1
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			x := 0
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // down: a line of b is added
+			} else {
+				x = v[offset+k-1] + 1 // right: a line of a is removed
+			}
+			y := x - k
+			for x < n && y < m && a[x].key == b[y].key {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	if !found {
+		return replaceAll(a, b)
+	}
+
+	// walks back from the end, collecting the edits in reverse order
+	reversed := make([]diffLine, 0, n+m)
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		previous := trace[d] // the furthest points of d-1 edits, indexed from -d
+		at := func(k int) int { return previous[k+d] }
+
+		k := x - y
+		previousK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			previousK = k + 1
+		}
+		previousX := at(previousK)
+		previousY := previousX - previousK
+
+		for x > previousX && y > previousY {
+			x--
+			y--
+			reversed = append(reversed, diffLine{op: diffKeep, raw: a[x].raw})
+		}
+		if x == previousX {
+			y--
+			reversed = append(reversed, diffLine{op: diffAdd, raw: b[y].raw})
+		} else {
+			x--
+			reversed = append(reversed, diffLine{op: diffRemove, raw: a[x].raw})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		reversed = append(reversed, diffLine{op: diffKeep, raw: a[x].raw})
+	}
+
+	result := make([]diffLine, len(reversed))
+	for i, line := range reversed {
+		result[len(reversed)-1-i] = line
+	}
+
+	return result
+}
+
+// Every line of a removed, then every line of b added
+func replaceAll(a, b []outputLine) []diffLine {
+	result := make([]diffLine, 0, len(a)+len(b))
+	for _, line := range a {
+		result = append(result, diffLine{op: diffRemove, raw: line.raw})
+	}
+	for _, line := range b {
+		result = append(result, diffLine{op: diffAdd, raw: line.raw})
+	}
+
+	return result
+}
+
+// The changed lines of a comparison, with diffContext unchanged lines
+// around them, in hunks introduced by a line like "@@ -12,7 +12,8 @@"
+func unifiedDiff(lines []diffLine) string {
+	builder := strings.Builder{}
+
+	for start := 0; start < len(lines); {
+		// the first change from start
+		first := start
+		for first < len(lines) && lines[first].op == diffKeep {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+
+		// the hunk goes on while changes are close enough to each other
+		from, to := max(first-diffContext, start), first
+		for i := first; i < len(lines) && i-to <= 2*diffContext; i++ {
+			if lines[i].op != diffKeep {
+				to = i + 1
+			}
+		}
+		to = min(to+diffContext, len(lines))
+
+		oldStart, newStart := lineNumbers(lines, from)
+		oldCount, newCount := 0, 0
+		for _, line := range lines[from:to] {
+			if line.op != diffAdd {
+				oldCount++
+			}
+			if line.op != diffRemove {
+				newCount++
+			}
+		}
+		builder.WriteString(diffHunkStyle.Render(
+			fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, oldCount, newStart, newCount),
+		) + "\n")
+
+		for _, line := range lines[from:to] {
+			switch line.op {
+			case diffRemove:
+				builder.WriteString(diffRemovedStyle.Render("-") + " ")
+			case diffAdd:
+				builder.WriteString(diffAddedStyle.Render("+") + " ")
+			default:
+				builder.WriteString("  ")
+			}
+			builder.WriteString(line.raw + resetSequence + "\n")
+		}
+
+		start = to
+	}
+
+	return builder.String()
+}
+
+// The numbers, from 1, of the line at i in the old run and in the new one
+func lineNumbers(lines []diffLine, i int) (int, int) {
+	oldNr, newNr := 1, 1
+	for _, line := range lines[:i] {
+		if line.op != diffAdd {
+			oldNr++
+		}
+		if line.op != diffRemove {
+			newNr++
+		}
+	}
+
+	return oldNr, newNr
+}
+
+// Number of lines added and removed
+func diffStat(lines []diffLine) (added int, removed int) {
+	for _, line := range lines {
+		switch line.op {
+		case diffAdd:
+			added++
+		case diffRemove:
+			removed++
+		}
+	}
+
+	return added, removed
+}
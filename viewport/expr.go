@@ -0,0 +1,239 @@
+package viewport
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gaelph/monique/filter"
+)
+
+// A comparison of a field of structured log lines with a value,
+// like "latency_ms>200"
+type comparison struct {
+	key      string
+	operator string
+	value    string
+	reg      *regexp.Regexp // for the ~ operator
+}
+
+// A filter on the fields of structured log lines, like
+// "level>=warn and service=api or status>=500": comparisons joined
+// with "and", which binds tighter than "or"
+type fieldExpr struct {
+	alternatives [][]comparison // any of them, all of their comparisons
+}
+
+// Longest first, so that ">=" is not taken for ">"
+var operators = []string{"!=", ">=", "<=", "=", ">", "<", "~"}
+
+var errNotExpression = errors.New("not a field expression")
+
+// Parses a field expression, without its prefix
+func parseFieldExpr(input string) (*fieldExpr, error) {
+	scanner := exprScanner{input: input}
+	expr := &fieldExpr{}
+	conjunction := []comparison{}
+
+	for {
+		cmp, err := scanner.comparison()
+		if err != nil {
+			return nil, err
+		}
+		conjunction = append(conjunction, cmp)
+
+		scanner.skipSpaces()
+		if scanner.done() {
+			break
+		}
+
+		switch strings.ToLower(scanner.key()) {
+		case "and":
+		case "or":
+			expr.alternatives = append(expr.alternatives, conjunction)
+			conjunction = []comparison{}
+		default:
+			return nil, errNotExpression
+		}
+	}
+	expr.alternatives = append(expr.alternatives, conjunction)
+
+	return expr, nil
+}
+
+// Reads a field expression, from left to right
+type exprScanner struct {
+	input string
+	pos   int
+}
+
+func (s *exprScanner) done() bool {
+	return s.pos >= len(s.input)
+}
+
+func (s *exprScanner) skipSpaces() {
+	for !s.done() && s.input[s.pos] == ' ' {
+		s.pos++
+	}
+}
+
+func (s *exprScanner) key() string {
+	start := s.pos
+	for !s.done() && isKeyByte(s.input[s.pos]) {
+		s.pos++
+	}
+
+	return s.input[start:s.pos]
+}
+
+// A comparison like "level>=warn", "latency_ms > 200" or `msg~"timed? out"`
+func (s *exprScanner) comparison() (comparison, error) {
+	s.skipSpaces()
+	cmp := comparison{key: s.key()}
+	if cmp.key == "" {
+		return comparison{}, errNotExpression
+	}
+
+	s.skipSpaces()
+	for _, op := range operators {
+		if strings.HasPrefix(s.input[s.pos:], op) {
+			cmp.operator = op
+			s.pos += len(op)
+			break
+		}
+	}
+	if cmp.operator == "" {
+		return comparison{}, errNotExpression
+	}
+
+	s.skipSpaces()
+	value, err := s.value()
+	if err != nil {
+		return comparison{}, err
+	}
+	cmp.value = value
+
+	if cmp.operator == "~" {
+		reg, err := filter.CompilePattern(cmp.value)
+		if err != nil {
+			return comparison{}, fmt.Errorf("%s~%s: %w", cmp.key, cmp.value, err)
+		}
+		cmp.reg = reg
+	}
+
+	return cmp, nil
+}
+
+// A quoted value, or the characters up to the next space
+func (s *exprScanner) value() (string, error) {
+	start := s.pos
+	if !s.done() && s.input[s.pos] == '"' {
+		for s.pos++; !s.done() && s.input[s.pos] != '"'; s.pos++ {
+			if s.input[s.pos] == '\\' {
+				s.pos++
+			}
+		}
+		if s.done() {
+			return "", errNotExpression
+		}
+		s.pos++
+
+		unquoted, err := strconv.Unquote(s.input[start:s.pos])
+		if err != nil {
+			return "", errNotExpression
+		}
+		return unquoted, nil
+	}
+
+	for !s.done() && s.input[s.pos] != ' ' {
+		s.pos++
+	}
+	if s.pos == start {
+		return "", errNotExpression
+	}
+
+	return s.input[start:s.pos], nil
+}
+
+// Whether the record passes the expression. Lines that are not
+// structured pass none
+func (e *fieldExpr) matches(rec *record) bool {
+	if rec == nil {
+		return false
+	}
+
+	for _, conjunction := range e.alternatives {
+		matches := true
+		for _, cmp := range conjunction {
+			if !cmp.matches(rec) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c comparison) matches(rec *record) bool {
+	value, ok := rec.get(c.key)
+	if !ok {
+		return c.operator == "!="
+	}
+
+	if c.operator == "~" {
+		return c.reg.MatchString(value)
+	}
+
+	return c.holds(compareValues(c.key, value, c.value))
+}
+
+// Whether the operator holds for the result of a comparison,
+// negative when the value of the field is lower
+func (c comparison) holds(order int) bool {
+	switch c.operator {
+	case "=":
+		return order == 0
+	case "!=":
+		return order != 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	}
+
+	return false
+}
+
+// Compares levels by severity, numbers by value, and the rest
+// as case insensitive strings
+func compareValues(key, a, b string) int {
+	if isOneOf(key, levelKeys) {
+		if levelA, levelB := parseLevel(a), parseLevel(b); levelA != noLevel && levelB != noLevel {
+			return int(levelA) - int(levelB)
+		}
+	}
+
+	numberA, errA := strconv.ParseFloat(a, 64)
+	numberB, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case numberA < numberB:
+			return -1
+		case numberA > numberB:
+			return 1
+		}
+		return 0
+	}
+
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
@@ -0,0 +1,135 @@
+package viewport
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseFieldExpr(t *testing.T) {
+	tests := []struct {
+		input string
+		want  [][]comparison // without their compiled patterns
+	}{
+		{"level>=warn", [][]comparison{{{key: "level", operator: ">=", value: "warn"}}}},
+		{"latency_ms > 200", [][]comparison{{{key: "latency_ms", operator: ">", value: "200"}}}},
+		{"http.status!=200", [][]comparison{{{key: "http.status", operator: "!=", value: "200"}}}},
+		{`msg~"timed? out"`, [][]comparison{{{key: "msg", operator: "~", value: "timed? out"}}}},
+		{`msg="say \"hi\""`, [][]comparison{{{key: "msg", operator: "=", value: `say "hi"`}}}},
+		{
+			"level>=warn and service=api",
+			[][]comparison{{
+				{key: "level", operator: ">=", value: "warn"},
+				{key: "service", operator: "=", value: "api"},
+			}},
+		},
+		{
+			"level=error OR status>=500 and path=/api",
+			[][]comparison{
+				{{key: "level", operator: "=", value: "error"}},
+				{
+					{key: "status", operator: ">=", value: "500"},
+					{key: "path", operator: "=", value: "/api"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		expr, err := parseFieldExpr(test.input)
+		if err != nil {
+			t.Errorf("parseFieldExpr(%q) failed: %v", test.input, err)
+			continue
+		}
+
+		if len(expr.alternatives) != len(test.want) {
+			t.Errorf("parseFieldExpr(%q) has %d alternatives, want %d", test.input, len(expr.alternatives), len(test.want))
+			continue
+		}
+		for i, conjunction := range expr.alternatives {
+			if len(conjunction) != len(test.want[i]) {
+				t.Errorf("parseFieldExpr(%q) alternative %d has %d comparisons, want %d", test.input, i, len(conjunction), len(test.want[i]))
+				continue
+			}
+			for j, cmp := range conjunction {
+				want := test.want[i][j]
+				if cmp.key != want.key || cmp.operator != want.operator || cmp.value != want.value {
+					t.Errorf("parseFieldExpr(%q) comparison %d.%d = %s %s %q, want %s %s %q", test.input, i, j,
+						cmp.key, cmp.operator, cmp.value, want.key, want.operator, want.value)
+				}
+				if (cmp.operator == "~") != (cmp.reg != nil) {
+					t.Errorf("parseFieldExpr(%q) comparison %d.%d compiled pattern %v", test.input, i, j, cmp.reg)
+				}
+			}
+		}
+	}
+}
+
+func TestParseFieldExprRejectsBadInput(t *testing.T) {
+	inputs := []string{
+		"",
+		"level",
+		"level>=",
+		">=warn",
+		"status=500 path=/api",
+		"status=500 and",
+		"level=warn xor status=500",
+		`msg="unterminated`,
+		"status=500 ERROR",
+	}
+
+	for _, input := range inputs {
+		if _, err := parseFieldExpr(input); !errors.Is(err, errNotExpression) {
+			t.Errorf("parseFieldExpr(%q) error = %v, want %v", input, err, errNotExpression)
+		}
+	}
+
+	if _, err := parseFieldExpr("msg~(unclosed"); err == nil {
+		t.Errorf("parseFieldExpr(%q) succeeded, want the error of the pattern", "msg~(unclosed")
+	}
+}
+
+func TestFieldExprMatches(t *testing.T) {
+	lines := map[string]string{
+		"slow":    `{"level":"warn","msg":"slow request","latency_ms":350,"service":"api"}`,
+		"fast":    `{"level":"info","msg":"request served","latency_ms":12,"service":"api"}`,
+		"failed":  `time=2024-01-01T12:00:00Z severity=ERROR message="timed out" status=504 service=worker`,
+		"verbose": `level=debug msg=tick service=scheduler`,
+		"plain":   `2024-01-01 12:00:00 ERROR not structured`,
+	}
+
+	tests := []struct {
+		expr string
+		want []string // the lines passing it
+	}{
+		{"level>=warn", []string{"failed", "slow"}},
+		{"level<info", []string{"verbose"}},
+		{"latency_ms>200", []string{"slow"}},
+		{"latency_ms<=12", []string{"fast"}},
+		{"service=API", []string{"fast", "slow"}},
+		{"service!=api", []string{"failed", "verbose"}},
+		{"status!=504", []string{"fast", "slow", "verbose"}},
+		{`msg~"timed? out"`, []string{"failed"}},
+		{"level>=warn and service=api", []string{"slow"}},
+		{"service=worker or latency_ms>200", []string{"failed", "slow"}},
+		{"level=debug or level>=warn and latency_ms>100", []string{"slow", "verbose"}},
+		{"missing=1", nil},
+	}
+
+	for _, test := range tests {
+		expr, err := parseFieldExpr(test.expr)
+		if err != nil {
+			t.Fatalf("parseFieldExpr(%q) failed: %v", test.expr, err)
+		}
+
+		var got []string
+		for _, name := range []string{"failed", "fast", "plain", "slow", "verbose"} {
+			if expr.matches(parseRecord(lines[name], 120)) {
+				got = append(got, name)
+			}
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%q matches %v, want %v", test.expr, got, test.want)
+		}
+	}
+}
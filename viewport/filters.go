@@ -0,0 +1,239 @@
+package viewport
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/gaelph/monique/filter"
+)
+
+// A filter term, with its compiled pattern or field expression
+type compiledTerm struct {
+	reg     *regexp.Regexp
+	expr    *fieldExpr
+	exclude bool
+}
+
+// Compiles the terms, leaving out the empty and invalid ones,
+// which filter nothing out
+func compileFilters(terms []filter.Term) []compiledTerm {
+	compiled := make([]compiledTerm, 0, len(terms))
+	for _, term := range terms {
+		if term.Pattern == "" {
+			continue
+		}
+
+		if source, ok := term.Expr(); ok {
+			expr, err := parseFieldExpr(source)
+			if err != nil {
+				continue
+			}
+			compiled = append(compiled, compiledTerm{expr: expr, exclude: term.Exclude})
+			continue
+		}
+
+		reg, err := filter.CompilePattern(term.Pattern)
+		if err != nil {
+			continue
+		}
+		compiled = append(compiled, compiledTerm{reg: reg, exclude: term.Exclude})
+	}
+
+	return compiled
+}
+
+// Whether a line passes every term: its text, as displayed, for patterns,
+// and the record of its structured log line for field expressions
+func matchesFilters(filters []compiledTerm, text string, rec *record) bool {
+	for _, term := range filters {
+		matches := false
+		if term.expr != nil {
+			matches = term.expr.matches(rec)
+		} else {
+			matches = term.reg.MatchString(text)
+		}
+
+		if matches == term.exclude {
+			return false
+		}
+	}
+
+	return true
+}
+
+// The filter terms in effect: the stack of the active pane, with the term
+// being typed in place of the one being edited, or on top of the others
+func (m model) activeFilters() []filter.Term {
+	terms := make([]filter.Term, 0, len(m.filters)+1)
+	for i, term := range m.filters {
+		if i != m.editingFilter {
+			terms = append(terms, term)
+		}
+	}
+	if m.filterString != "" {
+		terms = append(terms, filter.Parse(m.filterString))
+	}
+
+	return terms
+}
+
+// Like "ERROR|WARN · !healthcheck"
+func (m model) filtersStatus() string {
+	terms := make([]string, 0, len(m.filters)+1)
+	for _, term := range m.activeFilters() {
+		terms = append(terms, term.String())
+	}
+
+	return strings.Join(terms, " · ")
+}
+
+// MARK: - Filter Stack
+
+// Handles the keys of the filter stack popup
+func (m model) updateFilterStack(msg tea.KeyMsg) model {
+	switch {
+	case key.Matches(msg, m.keyMap.Blur), key.Matches(msg, m.keyMap.FilterStack):
+		m.showingFilters = false
+	case key.Matches(msg, m.keyMap.PreviousFilter):
+		m = m.selectFilter(-1)
+	case key.Matches(msg, m.keyMap.NextFilter):
+		m = m.selectFilter(1)
+	case key.Matches(msg, m.keyMap.RemoveFilter):
+		m = m.removeFilter()
+	case key.Matches(msg, m.keyMap.ToggleFilter):
+		m = m.toggleFilter()
+	case key.Matches(msg, m.keyMap.EditFilter):
+		m = m.editFilter()
+	case key.Matches(msg, m.keyMap.Filter):
+		m.showingFilters = false
+		m = m.startFilter()
+	}
+
+	return m
+}
+
+func (m model) openFilterStack() model {
+	m.showingFilters = true
+	m.filterCursor = clamp(m.filterCursor, 0, max(len(m.filters)-1, 0))
+
+	return m
+}
+
+// Moves the selection of the filter stack by offset, looping around
+func (m model) selectFilter(offset int) model {
+	if len(m.filters) > 0 {
+		m.filterCursor = clampLoop(m.filterCursor+offset, 0, len(m.filters)-1)
+	}
+
+	return m
+}
+
+func (m model) removeFilter() model {
+	if len(m.filters) == 0 {
+		return m
+	}
+
+	m.filters = append(m.filters[:m.filterCursor:m.filterCursor], m.filters[m.filterCursor+1:]...)
+	m.filterCursor = clamp(m.filterCursor, 0, max(len(m.filters)-1, 0))
+	m.refresh()
+
+	return m
+}
+
+// Turns the selected include term into an exclude one, and back
+func (m model) toggleFilter() model {
+	if len(m.filters) == 0 {
+		return m
+	}
+
+	m.filters[m.filterCursor].Exclude = !m.filters[m.filterCursor].Exclude
+	m.refresh()
+
+	return m
+}
+
+// Closes the popup, and edits the selected term in the text input
+func (m model) editFilter() model {
+	if len(m.filters) == 0 {
+		return m
+	}
+
+	m.showingFilters = false
+	m = m.startFilter()
+	m.editingFilter = m.filterCursor
+	m.filterString = m.filters[m.filterCursor].String()
+	m.textinput.SetValue(m.filterString)
+	m.textinput.CursorEnd()
+
+	return m
+}
+
+// Pushes the term being typed on the filter stack, or puts it in place
+// of the one being edited
+func (m model) pushFilter() model {
+	term := filter.Parse(m.filterString)
+	switch {
+	case term.Pattern == "" && m.editingFilter >= 0:
+		m.filters = append(m.filters[:m.editingFilter:m.editingFilter], m.filters[m.editingFilter+1:]...)
+	case term.Pattern == "":
+	case m.editingFilter >= 0:
+		m.filters[m.editingFilter] = term
+	default:
+		m.filters = append(m.filters, term)
+	}
+
+	m.editingFilter = -1
+	m.filterString = ""
+	m.textinput.SetValue("")
+
+	return m
+}
+
+func (m model) filterStackView() string {
+	k := m.keyMap
+
+	lines := []string{
+		headerStyle.Render("Filters"),
+		separatorStyle.Render("⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯"),
+	}
+	if len(m.filters) == 0 {
+		lines = append(lines, fmt.Sprintf("No filter, %s to add one", keysOf(k.Filter)))
+	}
+	for i, term := range m.filters {
+		kind := "include"
+		if term.Exclude {
+			kind = "exclude"
+		}
+
+		line := fmt.Sprintf("  %s  %s", kind, term.Pattern)
+		if i == m.filterCursor {
+			line = headerStyle.Render("> " + line[2:])
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines,
+		"",
+		separatorStyle.Render("⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯"),
+		helpEntry("previous", k.PreviousFilter),
+		helpEntry("next", k.NextFilter),
+		helpEntry("edit", k.EditFilter),
+		helpEntry("include/exclude", k.ToggleFilter),
+		helpEntry("remove", k.RemoveFilter),
+		helpEntry("close", k.Blur),
+	)
+
+	content := paragraphStyle.Render(strings.Join(lines, "\n"))
+	content = blockStyle.Render(content)
+
+	return lipgloss.Place(
+		m.viewport.Width, m.viewport.Height,
+		lipgloss.Center, lipgloss.Center,
+		content,
+	)
+}
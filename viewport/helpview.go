@@ -1,38 +1,59 @@
 package viewport
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/lipgloss"
 )
 
 func (m model) helpView() string {
+	k := m.keyMap
 	defaultKeys := strings.Join([]string{
 		headerStyle.Render("General"),
 		separatorStyle.Render("⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯"),
-		"[f]         filter",
-		"[/]         search",
-		"[ctrl+u]    scroll up",
-		"[ctrl+d]    scroll down",
-		"[ctrl+r]    restart the command",
-		"[ctrl+c]    quit",
+		helpEntry("filter", k.Filter),
+		helpEntry("filter stack", k.FilterStack),
+		helpEntry("more/less context", k.MoreContext, k.LessContext),
+		helpEntry("structured logs", k.ToggleStructured),
+		helpEntry("latest runs, compare runs", k.RunHistory),
+		helpEntry("previous/next run, with -keep", k.PreviousRunStart, k.NextRunStart),
+		helpEntry("hide lines below a level", k.ThresholdDebug, k.ThresholdInfo, k.ThresholdWarn, k.ThresholdError, k.ThresholdFatal),
+		helpEntry("search", k.Search),
+		helpEntry("scroll up", k.HalfPageUp),
+		helpEntry("scroll down", k.HalfPageDown),
+		helpEntry("restart the command", k.Restart),
+		helpEntry("cancel the pending restart", k.CancelRestart),
+		helpEntry("next command tab", k.NextPane),
+		helpEntry("quit", k.Quit),
 		"",
-		"[n]         go to next search match",
-		"[N]         go to previous search match",
+		helpEntry("go to next search match", k.NextMatch),
+		helpEntry("go to previous search match", k.PreviousMatch),
+		helpEntry("go to next/previous error", k.NextError, k.PreviousError),
+		helpEntry("open the error in $EDITOR", k.OpenError),
+		helpEntry("write the errors to a file", k.ExportErrors),
 	}, "\n")
 
 	inputKeys := strings.Join([]string{
 		headerStyle.Render("Search/Filter"),
 		separatorStyle.Render("⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯"),
-		"[esc]       cancel",
-		"[enter]     accept",
+		helpEntry("cancel", k.Blur),
+		helpEntry("accept", k.Accept),
 		"[ctrl+u]    clear field",
+		helpEntry("saved filters", k.PreviousSaved, k.NextSaved),
 		"",
 		"",
+		headerStyle.Render("Replay"),
+		separatorStyle.Render("⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯"),
+		helpEntry("pause/resume", k.PauseReplay),
+		helpEntry("10s backward", k.SeekBackward),
+		helpEntry("10s forward", k.SeekForward),
+		"",
 		"",
 		headerStyle.Render("This help"),
 		separatorStyle.Render("⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯"),
-		"[esc]  exit",
+		helpEntry("exit", k.Blur),
 	}, "\n")
 
 	content := lipgloss.JoinHorizontal(
@@ -57,3 +78,8 @@ func (m model) helpView() string {
 		content,
 	)
 }
+
+// Like "[ctrl+r]    restart the command"
+func helpEntry(description string, bindings ...key.Binding) string {
+	return fmt.Sprintf("%-12s%s", keysOf(bindings...), description)
+}
@@ -0,0 +1,374 @@
+package viewport
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+// Default number of runs kept by each pane
+const DefaultHistory = 10
+
+// A run of the command of a pane
+type run struct {
+	number  int                  // from 1, in the order the runs started
+	command string               // the command line, which {changed} makes differ between runs
+	started time.Time            // when the run started
+	changes mediator.ChangeSet   // the files whose changes triggered the run, if any
+	status  *mediator.ExitStatus // how the run ended, nil while it goes on
+	lines   *scrollback          // the output, once the next run started
+	start   int                  // the number of the first line of the run in lines
+	end     int                  // the number of the line after the last one of the run, once the next run started
+}
+
+// Like "14:02:11"
+func (r *run) startTime() string {
+	return r.started.Format(time.TimeOnly)
+}
+
+// Like "exit 1 · 2.31s", or "running"
+func (r *run) outcome() string {
+	if r.status == nil {
+		return "running"
+	}
+
+	return fmt.Sprintf("%s · %s", r.status.Short(), formatDuration(r.status.Duration))
+}
+
+// Like "api/handler.go, api/routes.go", empty when the run was not
+// triggered by changes
+func (r *run) trigger() string {
+	return r.changes.Names()
+}
+
+// Records the start of a run, keeping the output of the previous one,
+// and the last history runs at most
+func (p *pane) startRun(command string, changes mediator.ChangeSet, history int) {
+	number := 1
+	if latest := p.latestRun(); latest != nil {
+		latest.lines = p.output()
+		latest.end = p.output().end()
+		number = latest.number + 1
+	}
+
+	p.runs = append(p.runs, &run{
+		number:  number,
+		command: command,
+		started: time.Now(),
+		changes: changes,
+		start:   p.output().end(),
+	})
+	if history > 0 && len(p.runs) > history {
+		p.runs = slices.Delete(p.runs, 0, len(p.runs)-history)
+	}
+}
+
+// The run going on, or that ended last. nil before the command first started
+func (p *pane) latestRun() *run {
+	if len(p.runs) == 0 {
+		return nil
+	}
+
+	return p.runs[len(p.runs)-1]
+}
+
+// The run an exit belongs to: the latest one, or the previous one
+// while the latest one is in its pre-steps
+func (p *pane) exitedRun() *run {
+	if p.inSteps {
+		if len(p.runs) < 2 {
+			return nil
+		}
+		return p.runs[len(p.runs)-2]
+	}
+
+	return p.latestRun()
+}
+
+// The output of the latest run, even while an older run is displayed
+func (p *pane) output() *scrollback {
+	if p.live != nil {
+		return p.live
+	}
+
+	return p.allLines
+}
+
+// The output of a run kept in the history: its lines, from the line
+// numbered from to the one numbered to, excluded
+func (p *pane) runLines(r *run) (lines *scrollback, from int, to int) {
+	lines, to = r.lines, r.end
+	if r == p.latestRun() {
+		lines, to = p.output(), p.output().end()
+	}
+
+	return lines, max(r.start, lines.first()), to
+}
+
+// Whether an older run, or a comparison of runs, is displayed
+// instead of the output of the latest run
+func (p *pane) isBrowsing() bool {
+	return p.live != nil
+}
+
+// MARK: - Run picker
+
+func (m model) openRunPicker() model {
+	if m.isMerged() || len(m.runs) == 0 {
+		return m
+	}
+
+	m.showingRuns = true
+	m.runCursor = len(m.runs) - 1
+	if m.shownRun != nil {
+		if i := slices.Index(m.runs, m.shownRun); i >= 0 {
+			m.runCursor = i
+		}
+	}
+
+	return m
+}
+
+func (m model) updateRunPicker(msg tea.KeyMsg) (model, []tea.Cmd) {
+	var cmds []tea.Cmd
+
+	switch {
+	case key.Matches(msg, m.keyMap.Blur), key.Matches(msg, m.keyMap.RunHistory):
+		m.showingRuns = false
+	case key.Matches(msg, m.keyMap.PreviousRun):
+		m.runCursor = clampLoop(m.runCursor-1, 0, len(m.runs)-1)
+	case key.Matches(msg, m.keyMap.NextRun):
+		m.runCursor = clampLoop(m.runCursor+1, 0, len(m.runs)-1)
+	case key.Matches(msg, m.keyMap.MarkRun):
+		selected := m.runs[m.runCursor]
+		if m.markedRun == selected {
+			m.markedRun = nil
+		} else {
+			m.markedRun = selected
+		}
+	case key.Matches(msg, m.keyMap.ViewRun):
+		m.showingRuns = false
+		cmds = m.showRun(m.runs[m.runCursor], cmds)
+	case key.Matches(msg, m.keyMap.DiffRuns):
+		if base := m.diffBase(); base != nil {
+			m.showingRuns = false
+			m.showDiff(base, m.runs[m.runCursor])
+			cmds = m.goToTop(cmds)
+		}
+	}
+
+	return m, cmds
+}
+
+// The run the selected one is compared with: the marked run,
+// or the one before the selected run
+func (m model) diffBase() *run {
+	selected := m.runs[m.runCursor]
+	if m.markedRun != nil && m.markedRun != selected && slices.Contains(m.runs, m.markedRun) {
+		return m.markedRun
+	}
+	if m.runCursor > 0 {
+		return m.runs[m.runCursor-1]
+	}
+
+	return nil
+}
+
+// Displays the output of a run, that of the latest one following
+// the output of the command again
+func (m *model) showRun(r *run, cmds []tea.Cmd) []tea.Cmd {
+	// the runs follow each other in the output
+	if m.keep {
+		m.showLatest()
+		return m.goToRunHeader(r.start, cmds)
+	}
+
+	if r == m.latestRun() {
+		m.showLatest()
+		return m.goToBottom(cmds)
+	}
+
+	m.browse(r.lines)
+	m.shownRun, m.comparedRun = r, nil
+	m.refresh()
+
+	return m.goToBottom(cmds)
+}
+
+// Displays the lines added and removed from the output of base
+// to that of r
+func (m *model) showDiff(base, r *run) {
+	// the older run is the base of the comparison
+	if base.number > r.number {
+		base, r = r, base
+	}
+
+	lines := diffLines(outputLines(m.runLines(base)), outputLines(m.runLines(r)))
+
+	added, removed := diffStat(lines)
+
+	diff := newScrollback(0)
+	content := unifiedDiff(lines)
+	if content == "" {
+		content = fmt.Sprintf("No difference between the output of run #%d and run #%d\n", base.number, r.number)
+	}
+	diff.appendStyled(content, m.contentWidth(), nil)
+
+	m.browse(diff)
+	m.shownRun, m.comparedRun = r, base
+	m.diffAdded, m.diffRemoved = added, removed
+	m.refresh()
+}
+
+// Displays lines instead of the output of the latest run,
+// which is kept following the command
+func (m *model) browse(lines *scrollback) {
+	if m.live == nil {
+		m.live = m.allLines
+	}
+	m.allLines = lines
+	m.activeError = -1
+	m.activeMatch = -1
+}
+
+// Displays the output of the latest run again
+func (m *model) showLatest() {
+	if m.live != nil {
+		m.allLines = m.live
+		m.live = nil
+	}
+	m.shownRun, m.comparedRun = nil, nil
+	m.activeError = -1
+	m.activeMatch = -1
+	m.refresh()
+}
+
+func (m model) runPickerView() string {
+	k := m.keyMap
+
+	lines := []string{
+		headerStyle.Render("Runs"),
+		separatorStyle.Render("⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯"),
+	}
+	for i, r := range m.runs {
+		mark := " "
+		if r == m.markedRun {
+			mark = "*"
+		}
+
+		outcome := r.outcome()
+		outcome += strings.Repeat(" ", max(16-lipgloss.Width(outcome), 0))
+
+		line := fmt.Sprintf("  %s#%-3d %s  %s %s", mark, r.number, r.startTime(), outcome, r.trigger())
+		line = truncate.StringWithTail(line, uint(max(m.viewport.Width-12, 20)), "…")
+		if i == m.runCursor {
+			line = headerStyle.Render("> " + line[2:])
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines,
+		"",
+		separatorStyle.Render("⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯⎯"),
+		helpEntry("previous", k.PreviousRun),
+		helpEntry("next", k.NextRun),
+		helpEntry("view", k.ViewRun),
+		helpEntry("compare with the marked or previous run", k.DiffRuns),
+		helpEntry("mark for comparison", k.MarkRun),
+		helpEntry("close", k.Blur),
+	)
+
+	content := paragraphStyle.Render(strings.Join(lines, "\n"))
+	content = blockStyle.Render(content)
+
+	return lipgloss.Place(
+		m.viewport.Width, m.viewport.Height,
+		lipgloss.Center, lipgloss.Center,
+		content,
+	)
+}
+
+// Like "Run #3 · 14:02:11 · exit 1 · 2.31s", or "Diff #3 → #5: +4 -2"
+func (m model) browsingStatus() string {
+	switch {
+	case m.comparedRun != nil:
+		return fmt.Sprintf("Diff #%d → #%d: +%d -%d", m.comparedRun.number, m.shownRun.number, m.diffAdded, m.diffRemoved)
+	case m.shownRun != nil:
+		return fmt.Sprintf("Run #%d · %s · %s", m.shownRun.number, m.shownRun.startTime(), m.shownRun.outcome())
+	}
+
+	return ""
+}
+
+// MARK: - Run headers
+
+// Appends the header of the run that started to the output of the source
+// pane p, and to the merged pane
+func (m *model) appendRunHeader(p *pane, shouldBottom bool, cmds []tea.Cmd) []tea.Cmd {
+	latest := p.latestRun()
+	header := runHeader(latest)
+
+	from := 0
+	for _, target := range m.targets(p.name) {
+		n, headerNr := target.appendRunHeader(p.name, header, m.contentWidth())
+		if target == m.pane {
+			from = n
+		}
+		if target == p {
+			latest.start = headerNr
+		}
+	}
+
+	return m.showAppended(p.name, from, shouldBottom, cmds)
+}
+
+// Scrolls to the header of the run numbered lineNr, if it is displayed
+func (m *model) goToRunHeader(lineNr int, cmds []tea.Cmd) []tea.Cmd {
+	i := sort.SearchInts(m.filteredIndices, lineNr)
+	if i == len(m.filteredIndices) || m.filteredIndices[i] != lineNr {
+		return cmds
+	}
+
+	m.viewport.SetYOffset(m.rowOf(i))
+	m.scrollPos = m.viewport.YOffset
+
+	return cmds
+}
+
+// Scrolls to the next run header below the top of the viewport, offset
+// being 1, or to the previous one above it, offset being -1
+func (m *model) goToRunStart(offset int, cmds []tea.Cmd) []tea.Cmd {
+	top := m.viewport.YOffset
+	target := -1
+	for i, lineNr := range m.filteredIndices {
+		if !m.allLines.at(lineNr).runHeader {
+			continue
+		}
+
+		row := m.rowOf(i)
+		if offset > 0 && row > top {
+			target = row
+			break
+		}
+		if offset < 0 && row < top {
+			target = row
+		}
+	}
+	if target < 0 {
+		return cmds
+	}
+
+	m.viewport.SetYOffset(target)
+	m.scrollPos = m.viewport.YOffset
+
+	return cmds
+}
@@ -1,33 +1,189 @@
 package viewport
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
 
 type KeyMap struct {
-	Blur          key.Binding
-	Search        key.Binding
-	Filter        key.Binding
-	Accept        key.Binding
-	NextMatch     key.Binding
-	PreviousMatch key.Binding
-	Quit          key.Binding
-	HalfPageUp    key.Binding
-	HalfPageDown  key.Binding
-	Restart       key.Binding
-	ShowHelp      key.Binding
+	Blur             key.Binding
+	Search           key.Binding
+	Filter           key.Binding
+	Accept           key.Binding
+	NextMatch        key.Binding
+	PreviousMatch    key.Binding
+	Quit             key.Binding
+	HalfPageUp       key.Binding
+	HalfPageDown     key.Binding
+	Restart          key.Binding
+	CancelRestart    key.Binding
+	NextPane         key.Binding
+	PreviousPane     key.Binding
+	ShowHelp         key.Binding
+	PreviousSaved    key.Binding
+	NextSaved        key.Binding
+	FilterStack      key.Binding
+	PreviousFilter   key.Binding
+	NextFilter       key.Binding
+	EditFilter       key.Binding
+	ToggleFilter     key.Binding
+	RemoveFilter     key.Binding
+	MoreContext      key.Binding
+	LessContext      key.Binding
+	ToggleStructured key.Binding
+	ThresholdDebug   key.Binding
+	ThresholdInfo    key.Binding
+	ThresholdWarn    key.Binding
+	ThresholdError   key.Binding
+	ThresholdFatal   key.Binding
+	NextError        key.Binding
+	PreviousError    key.Binding
+	OpenError        key.Binding
+	ExportErrors     key.Binding
+	RunHistory       key.Binding
+	PreviousRun      key.Binding
+	NextRun          key.Binding
+	ViewRun          key.Binding
+	DiffRuns         key.Binding
+	MarkRun          key.Binding
+	PreviousRunStart key.Binding
+	NextRunStart     key.Binding
+	PauseReplay      key.Binding
+	SeekBackward     key.Binding
+	SeekForward      key.Binding
 }
 
 func DefaultKeyBinding() KeyMap {
 	return KeyMap{
-		Blur:          key.NewBinding(key.WithKeys("esc")),
-		Search:        key.NewBinding(key.WithKeys("/")),
-		Filter:        key.NewBinding(key.WithKeys("f")),
-		Accept:        key.NewBinding(key.WithKeys("enter")),
-		NextMatch:     key.NewBinding(key.WithKeys("n")),
-		PreviousMatch: key.NewBinding(key.WithKeys("N")),
-		Quit:          key.NewBinding(key.WithKeys("ctrl+c")),
-		HalfPageUp:    key.NewBinding(key.WithKeys("ctrl+u")),
-		HalfPageDown:  key.NewBinding(key.WithKeys("ctrl+d")),
-		Restart:       key.NewBinding(key.WithKeys("ctrl+r")),
-		ShowHelp:      key.NewBinding(key.WithKeys("?")),
+		Blur:             key.NewBinding(key.WithKeys("esc")),
+		Search:           key.NewBinding(key.WithKeys("/")),
+		Filter:           key.NewBinding(key.WithKeys("f")),
+		Accept:           key.NewBinding(key.WithKeys("enter")),
+		NextMatch:        key.NewBinding(key.WithKeys("n")),
+		PreviousMatch:    key.NewBinding(key.WithKeys("N")),
+		Quit:             key.NewBinding(key.WithKeys("ctrl+c")),
+		HalfPageUp:       key.NewBinding(key.WithKeys("ctrl+u")),
+		HalfPageDown:     key.NewBinding(key.WithKeys("ctrl+d")),
+		Restart:          key.NewBinding(key.WithKeys("ctrl+r")),
+		CancelRestart:    key.NewBinding(key.WithKeys("x")),
+		NextPane:         key.NewBinding(key.WithKeys("tab")),
+		PreviousPane:     key.NewBinding(key.WithKeys("shift+tab")),
+		ShowHelp:         key.NewBinding(key.WithKeys("?")),
+		PreviousSaved:    key.NewBinding(key.WithKeys("up")),
+		NextSaved:        key.NewBinding(key.WithKeys("down")),
+		FilterStack:      key.NewBinding(key.WithKeys("F")),
+		PreviousFilter:   key.NewBinding(key.WithKeys("up", "k")),
+		NextFilter:       key.NewBinding(key.WithKeys("down", "j")),
+		EditFilter:       key.NewBinding(key.WithKeys("e", "enter")),
+		ToggleFilter:     key.NewBinding(key.WithKeys("!")),
+		RemoveFilter:     key.NewBinding(key.WithKeys("d", "delete")),
+		MoreContext:      key.NewBinding(key.WithKeys("+", "=")),
+		LessContext:      key.NewBinding(key.WithKeys("-")),
+		ToggleStructured: key.NewBinding(key.WithKeys("s")),
+		ThresholdDebug:   key.NewBinding(key.WithKeys("1")),
+		ThresholdInfo:    key.NewBinding(key.WithKeys("2")),
+		ThresholdWarn:    key.NewBinding(key.WithKeys("3")),
+		ThresholdError:   key.NewBinding(key.WithKeys("4")),
+		ThresholdFatal:   key.NewBinding(key.WithKeys("5")),
+		NextError:        key.NewBinding(key.WithKeys("e")),
+		PreviousError:    key.NewBinding(key.WithKeys("E")),
+		OpenError:        key.NewBinding(key.WithKeys("o")),
+		ExportErrors:     key.NewBinding(key.WithKeys("w")),
+		RunHistory:       key.NewBinding(key.WithKeys("h")),
+		PreviousRun:      key.NewBinding(key.WithKeys("up", "k")),
+		NextRun:          key.NewBinding(key.WithKeys("down", "j")),
+		ViewRun:          key.NewBinding(key.WithKeys("enter")),
+		DiffRuns:         key.NewBinding(key.WithKeys("d")),
+		MarkRun:          key.NewBinding(key.WithKeys("m")),
+		PreviousRunStart: key.NewBinding(key.WithKeys("[")),
+		NextRunStart:     key.NewBinding(key.WithKeys("]")),
+		PauseReplay:      key.NewBinding(key.WithKeys("p")),
+		SeekBackward:     key.NewBinding(key.WithKeys("left")),
+		SeekForward:      key.NewBinding(key.WithKeys("right")),
+	}
+}
+
+// The bindings by the name of their action, as used in configuration files
+func (k *KeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"cancel":             &k.Blur,
+		"search":             &k.Search,
+		"filter":             &k.Filter,
+		"accept":             &k.Accept,
+		"next-match":         &k.NextMatch,
+		"previous-match":     &k.PreviousMatch,
+		"quit":               &k.Quit,
+		"half-page-up":       &k.HalfPageUp,
+		"half-page-down":     &k.HalfPageDown,
+		"restart":            &k.Restart,
+		"cancel-restart":     &k.CancelRestart,
+		"next-pane":          &k.NextPane,
+		"previous-pane":      &k.PreviousPane,
+		"help":               &k.ShowHelp,
+		"previous-saved":     &k.PreviousSaved,
+		"next-saved":         &k.NextSaved,
+		"filter-stack":       &k.FilterStack,
+		"previous-filter":    &k.PreviousFilter,
+		"next-filter":        &k.NextFilter,
+		"edit-filter":        &k.EditFilter,
+		"toggle-filter":      &k.ToggleFilter,
+		"remove-filter":      &k.RemoveFilter,
+		"more-context":       &k.MoreContext,
+		"less-context":       &k.LessContext,
+		"structured":         &k.ToggleStructured,
+		"level-debug":        &k.ThresholdDebug,
+		"level-info":         &k.ThresholdInfo,
+		"level-warn":         &k.ThresholdWarn,
+		"level-error":        &k.ThresholdError,
+		"level-fatal":        &k.ThresholdFatal,
+		"next-error":         &k.NextError,
+		"previous-error":     &k.PreviousError,
+		"open-error":         &k.OpenError,
+		"export-errors":      &k.ExportErrors,
+		"runs":               &k.RunHistory,
+		"previous-run":       &k.PreviousRun,
+		"next-run":           &k.NextRun,
+		"view-run":           &k.ViewRun,
+		"diff-runs":          &k.DiffRuns,
+		"mark-run":           &k.MarkRun,
+		"previous-run-start": &k.PreviousRunStart,
+		"next-run-start":     &k.NextRunStart,
+		"pause-replay":       &k.PauseReplay,
+		"seek-backward":      &k.SeekBackward,
+		"seek-forward":       &k.SeekForward,
 	}
 }
+
+// Binds the named action to keys, replacing its default keys
+func (k *KeyMap) Rebind(action string, keys ...string) error {
+	binding, ok := k.actions()[action]
+	if !ok {
+		names := make([]string, 0)
+		for name := range k.actions() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return fmt.Errorf(
+			"unknown action %q, expected one of %s",
+			action,
+			strings.Join(names, ", "),
+		)
+	}
+
+	binding.SetKeys(keys...)
+	return nil
+}
+
+// Like "[ctrl+r]", or "[n/N]" for several bindings
+func keysOf(bindings ...key.Binding) string {
+	keys := make([]string, 0, len(bindings))
+	for _, binding := range bindings {
+		keys = append(keys, binding.Keys()...)
+	}
+
+	return "[" + strings.Join(keys, "/") + "]"
+}
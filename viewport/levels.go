@@ -0,0 +1,162 @@
+package viewport
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Severity of a log line, from the least to the most severe
+type level int
+
+const (
+	noLevel level = iota
+	traceLevel
+	debugLevel
+	infoLevel
+	warnLevel
+	errorLevel
+	fatalLevel
+)
+
+func (l level) String() string {
+	switch l {
+	case traceLevel:
+		return "trace"
+	case debugLevel:
+		return "debug"
+	case infoLevel:
+		return "info"
+	case warnLevel:
+		return "warn"
+	case errorLevel:
+		return "error"
+	case fatalLevel:
+		return "fatal"
+	}
+
+	return ""
+}
+
+// Parses a level name, like "warn", "WARNING" or "E", noLevel if unknown
+func parseLevel(name string) level {
+	switch strings.ToLower(name) {
+	case "trace", "trc", "t":
+		return traceLevel
+	case "debug", "dbg", "d":
+		return debugLevel
+	case "info", "inf", "i", "notice":
+		return infoLevel
+	case "warn", "warning", "wrn", "w":
+		return warnLevel
+	case "error", "err", "e":
+		return errorLevel
+	case "fatal", "ftl", "f", "panic", "dpanic", "critical", "crit", "emerg", "alert":
+		return fatalLevel
+	}
+
+	return noLevel
+}
+
+// Patterns telling the level of the lines of plain text output
+var levelPatterns = map[level]*regexp.Regexp{
+	traceLevel: regexp.MustCompile(`\bTRACE\b`),
+	debugLevel: regexp.MustCompile(`\b(DEBUG|DBG)\b`),
+	infoLevel:  regexp.MustCompile(`\b(INFO|INF)\b`),
+	warnLevel:  regexp.MustCompile(`\b(WARN|WARNING|WRN)\b`),
+	errorLevel: regexp.MustCompile(`\b(ERROR|ERR)\b`),
+	fatalLevel: regexp.MustCompile(`\b(FATAL|PANIC|CRITICAL|FTL)\b|^panic: `),
+}
+
+// Replaces the patterns telling the level of lines, by level name,
+// like "warn" or "error". The levels left out keep their default pattern
+func SetLevelPatterns(patterns map[string]string) error {
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		l := parseLevel(name)
+		if l == noLevel {
+			return fmt.Errorf("unknown level %q, expected one of trace, debug, info, warn, error, fatal", name)
+		}
+
+		reg, err := regexp.Compile(patterns[name])
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		levelPatterns[l] = reg
+	}
+
+	return nil
+}
+
+// The level of an output line: the level field of its record, if it is
+// structured, or that whose pattern matches first
+func detectLevel(text string, rec *record) level {
+	if rec != nil && rec.level != "" {
+		return parseLevel(rec.level)
+	}
+
+	detected, first := noLevel, len(text)
+	for l, reg := range levelPatterns {
+		loc := reg.FindStringIndex(text)
+		if loc != nil && (loc[0] < first || (loc[0] == first && l > detected)) {
+			detected, first = l, loc[0]
+		}
+	}
+
+	return detected
+}
+
+// Width of the gutter showing the level of lines, on their left
+const gutterWidth = 2
+
+// Like a red bar for an error line, blank for a line without a level
+func gutter(l level) string {
+	if l == noLevel {
+		return strings.Repeat(" ", gutterWidth)
+	}
+
+	return levelStyle(l.String()).Render("▌") + strings.Repeat(" ", gutterWidth-1)
+}
+
+// Hides the lines below l, or shows every line again if l already was
+// the threshold
+func (m model) setLevelThreshold(l level) model {
+	if m.minLevel == l {
+		l = noLevel
+	}
+	m.minLevel = l
+	m.refresh()
+
+	return m
+}
+
+// Whether the line is hidden, as its level is below the threshold.
+// Lines without a level are always displayed
+func (m model) isBelowThreshold(l level) bool {
+	return l != noLevel && l < m.minLevel
+}
+
+func levelStyle(name string) lipgloss.Style {
+	switch parseLevel(name) {
+	case traceLevel, debugLevel:
+		return debugLevelStyle
+	case infoLevel:
+		return infoLevelStyle
+	case warnLevel:
+		return warnLevelStyle
+	case errorLevel:
+		return errorLevelStyle
+	case fatalLevel:
+		return fatalLevelStyle
+	}
+
+	return lipgloss.NewStyle()
+}
@@ -0,0 +1,324 @@
+package viewport
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A location in a source file, as reported by a compiler, a linter
+// or a test runner
+type location struct {
+	file    string
+	line    int
+	col     int    // 0 if unknown
+	message string // what is wrong there, if given on the same line
+	start   int    // start of the location in the text of the line
+	end     int    // end of the location in the text of the line
+}
+
+var (
+	// Go, gcc/clang, pytest, eslint's unix format, tsc's pretty format,
+	// and rustc's "--> src/main.rs:12:5"
+	//   main.go:12:3: undefined: x
+	//   src/app.ts:12:5 - error TS2322: ...
+	colonLocation = regexp.MustCompile(`^\s*(?:--> )?(?P<file>[^\s:()"'<>]+\.[A-Za-z]\w*):(?P<line>\d+)(?::(?P<col>\d+))?(?::\s*|\s+-\s+|\s*$)(?P<message>.*)$`)
+
+	// tsc's classic format
+	//   src/app.ts(12,5): error TS2322: ...
+	parenLocation = regexp.MustCompile(`^\s*(?P<file>[^\s:()"'<>]+\.[A-Za-z]\w*)\((?P<line>\d+),(?P<col>\d+)\):\s*(?P<message>.*)$`)
+
+	// Python tracebacks
+	//   File "app/models.py", line 12, in save
+	pythonLocation = regexp.MustCompile(`^\s*File "(?P<file>[^"]+)", line (?P<line>\d+)(?:, in (?P<message>.*))?$`)
+
+	// eslint's stylish format, under a line with the path of the file
+	//   12:5  error  'x' is not defined  no-undef
+	stylishEntry = regexp.MustCompile(`^\s+(?P<line>\d+):(?P<col>\d+)\s+(?P<message>(?:error|warning)\s+.*)$`)
+	stylishFile  = regexp.MustCompile(`^\S+\.[A-Za-z]\w*$`)
+
+	// rustc's message, on the lines before its location
+	//   error[E0425]: cannot find value `x` in this scope
+	rustMessage = regexp.MustCompile(`^(?:error|warning)(?:\[\w+\])?: .*$`)
+)
+
+// How many lines back the file of eslint's stylish format, or the message
+// of rustc, are looked for
+const locationLookBehind = 200
+
+// Parses the location in the output line numbered n, whose text is text.
+// Returns nil if there is none
+func parseLocation(lines *scrollback, n int, text string) *location {
+	for _, reg := range []*regexp.Regexp{colonLocation, parenLocation, pythonLocation} {
+		if loc := matchLocation(reg, text); loc != nil {
+			if loc.message == "" && strings.Contains(text, "--> ") {
+				loc.message = lookBehind(lines, n, rustMessage)
+			}
+			return loc
+		}
+	}
+
+	if loc := matchLocation(stylishEntry, text); loc != nil {
+		if loc.file = stylishFileOf(lines, n); loc.file != "" {
+			return loc
+		}
+	}
+
+	return nil
+}
+
+// The location matched by reg, from its file, line, col and message groups.
+// The location spans from the file, or the line, to the column, or the line
+func matchLocation(reg *regexp.Regexp, text string) *location {
+	groups := reg.FindStringSubmatchIndex(text)
+	if groups == nil {
+		return nil
+	}
+	// start and end of the named group, -1 if it did not match
+	bounds := func(name string) (int, int) {
+		i := reg.SubexpIndex(name)
+		if i < 0 {
+			return -1, -1
+		}
+		return groups[2*i], groups[2*i+1]
+	}
+
+	loc := &location{}
+	fileStart, fileEnd := bounds("file")
+	lineStart, lineEnd := bounds("line")
+	colStart, colEnd := bounds("col")
+	messageStart, messageEnd := bounds("message")
+
+	loc.start, loc.end = lineStart, lineEnd
+	if fileStart >= 0 {
+		loc.file = text[fileStart:fileEnd]
+		loc.start = fileStart
+	}
+	loc.line, _ = strconv.Atoi(text[lineStart:lineEnd])
+	if colStart >= 0 {
+		loc.col, _ = strconv.Atoi(text[colStart:colEnd])
+		loc.end = colEnd
+	}
+	if messageStart >= 0 {
+		loc.message = text[messageStart:messageEnd]
+	}
+
+	return loc
+}
+
+// The file of an entry of eslint's stylish format: the line with the path
+// alone, before the entries of the file
+func stylishFileOf(lines *scrollback, n int) string {
+	for i := n - 1; i >= max(lines.first(), n-locationLookBehind); i-- {
+		text := lines.at(i).text
+		switch {
+		case stylishEntry.MatchString(text):
+			continue
+		case stylishFile.MatchString(text):
+			return text
+		}
+		return ""
+	}
+
+	return ""
+}
+
+// The first match of reg in the lines before the one numbered n
+func lookBehind(lines *scrollback, n int, reg *regexp.Regexp) string {
+	for i := n - 1; i >= max(lines.first(), n-locationLookBehind); i-- {
+		if match := reg.FindString(lines.at(i).text); match != "" {
+			return match
+		}
+	}
+
+	return ""
+}
+
+// Like "api/handler.go:12:3"
+func (l *location) String() string {
+	if l.col > 0 {
+		return fmt.Sprintf("%s:%d:%d", l.file, l.line, l.col)
+	}
+	return fmt.Sprintf("%s:%d", l.file, l.line)
+}
+
+// Absolute path of the file, relative paths being relative to the
+// working directory, where commands are run
+func (l *location) path() string {
+	path, err := filepath.Abs(l.file)
+	if err != nil {
+		return l.file
+	}
+
+	return path
+}
+
+// The location, as an OSC 8 hyperlink to its file, for terminals that
+// support them
+func (l *location) hyperlink() string {
+	target := url.URL{Scheme: "file", Path: l.path()}
+
+	return fmt.Sprintf("\x1b]8;;%s\x1b\\%s\x1b]8;;\x1b\\", target.String(), l.String())
+}
+
+// The command opening the location in $VISUAL or $EDITOR, nil if neither is set
+func (l *location) editorCommand() *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return nil
+	}
+
+	switch filepath.Base(args[0]) {
+	// editors taking "file:line:col"
+	case "code", "codium", "cursor", "zed":
+		args = append(args, "--goto", fmt.Sprintf("%s:%d:%d", l.path(), l.line, max(l.col, 1)))
+	case "subl", "hx", "helix":
+		args = append(args, fmt.Sprintf("%s:%d:%d", l.path(), l.line, max(l.col, 1)))
+	// vi, vim, nvim, emacs, nano, micro, kak and the like
+	default:
+		args = append(args, fmt.Sprintf("+%d", l.line), l.path())
+	}
+
+	return exec.Command(args[0], args[1:]...)
+}
+
+// MARK: - Navigation
+
+// The editor opened with openError has exited
+type editorExitMsg struct {
+	err error
+}
+
+// The location of the output line numbered lineNr, nil if it has none
+func (m model) locationAt(lineNr int) *location {
+	if lineNr < m.allLines.first() || lineNr >= m.allLines.end() {
+		return nil
+	}
+
+	line := m.allLines.at(lineNr)
+	if line.continued {
+		return nil
+	}
+
+	return line.location
+}
+
+// Selects the next displayed location, offset being 1, or the previous one,
+// offset being -1, looping around
+func (m *model) goToError(offset int, cmds []tea.Cmd) []tea.Cmd {
+	count := len(m.filteredIndices)
+	if count == 0 {
+		return cmds
+	}
+
+	current := sort.SearchInts(m.filteredIndices, m.activeError)
+	if m.activeError < 0 && offset < 0 {
+		current = count
+	}
+	if offset > 0 && (current == count || m.filteredIndices[current] != m.activeError) {
+		// the selected line is not displayed anymore, the one after it is next
+		current--
+	}
+
+	for step := 1; step <= count; step++ {
+		i := ((current+offset*step)%count + count) % count
+		if m.locationAt(m.filteredIndices[i]) == nil {
+			continue
+		}
+
+		previous := m.activeError
+		m.activeError = m.filteredIndices[i]
+		m.renderLines(previous, m.activeError)
+
+		return m.goToLine(m.rowOf(i), cmds)
+	}
+
+	return cmds
+}
+
+// Opens the selected location in $VISUAL or $EDITOR, the user interface
+// being suspended until the editor exits
+func (m model) openError(cmds []tea.Cmd) []tea.Cmd {
+	loc := m.locationAt(m.activeError)
+	if loc == nil {
+		return cmds
+	}
+
+	command := loc.editorCommand()
+	if command == nil {
+		return cmds
+	}
+
+	return append(cmds, tea.ExecProcess(command, func(err error) tea.Msg {
+		return editorExitMsg{err: err}
+	}))
+}
+
+// Highlights the location in the displayed line, if it is on it
+func highlightLocation(line styledLine, loc *location, active bool) styledLine {
+	if loc.end > len(line.text) {
+		return line
+	}
+
+	seq := "\x1b[4m"
+	if active {
+		seq = "\x1b[4;7m"
+	}
+
+	escapes := make([]escape, 0, len(line.escapes)+2)
+	added := 0
+	for _, esc := range line.escapes {
+		if added == 0 && esc.pos > loc.start {
+			escapes = append(escapes, escape{pos: loc.start, seq: seq, sgr: true})
+			added++
+		}
+		if added == 1 && esc.pos >= loc.end {
+			escapes = append(escapes, escape{pos: loc.end, seq: "\x1b[24;27m", sgr: true})
+			added++
+		}
+		escapes = append(escapes, esc)
+	}
+	if added == 0 {
+		escapes = append(escapes, escape{pos: loc.start, seq: seq, sgr: true})
+	}
+	if added <= 1 {
+		escapes = append(escapes, escape{pos: loc.end, seq: "\x1b[24;27m", sgr: true})
+	}
+	line.escapes = escapes
+
+	return line
+}
+
+// Like "api/handler.go:12:3 undefined: x", the location being a hyperlink
+func (m model) errorView() string {
+	loc := m.locationAt(m.activeError)
+	if loc == nil {
+		return ""
+	}
+
+	message := ""
+	if loc.message != "" {
+		message = " " + loc.message
+	}
+
+	// hyperlinks are counted in the width of lines, which are truncated
+	// to the width of the terminal
+	link := loc.hyperlink()
+	if len(link)+len(message) > m.viewport.Width {
+		return loc.String() + message
+	}
+
+	return link + message
+}
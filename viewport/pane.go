@@ -0,0 +1,202 @@
+package viewport
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gaelph/monique/filter"
+	"github.com/gaelph/monique/mediator"
+)
+
+// A command whose output is displayed in its own pane
+type Source struct {
+	Name     string            // short name, displayed in tabs and as a prefix in the merged pane
+	Command  string            // the command that is run
+	Mediator mediator.Mediator // Communication Hub for this command
+	Input    bool              // whether it is the standard input, read once, instead of a command
+}
+
+// Output of a command, with its own filter and search state
+type pane struct {
+	name            string                     // name of the source, empty for the merged pane
+	mediator        mediator.Mediator          // Communication Hub, nil for the merged pane
+	command         string                     // the command that was run
+	searchString    string                     // the string to search for (displays matches)
+	filterString    string                     // the filter term being typed, not yet on the filter stack
+	filters         []filter.Term              // the filter stack, every term of which lines must pass
+	searchResults   []searchMatch              // the search results
+	allLines        *scrollback                // the whole content, up to the scrollback size
+	matchedLines    []int                      // numbers of the lines that pass the filter terms
+	filteredIndices []int                      // numbers of the displayed lines: matching lines, and their context
+	hunkStarts      []int                      // indices in filteredIndices of the lines following a gap, when context is displayed
+	renderedLines   []string                   // the rendered content (filtered with search decorations)
+	scrollPos       int                        // current scroll position (although, it should match viewport.YOffset)
+	activeMatch     int                        // the index of the active search match (in searchResults)
+	activeError     int                        // the number of the line of the selected source location, -1 if none
+	runStart        int                        // the number of the first line of the latest run of the command
+	input           bool                       // whether the output is that of the standard input
+	closed          bool                       // whether the end of the standard input was reached
+	runs            []*run                     // the latest runs of the command, the last one going on or ended last
+	live            *scrollback                // the output of the latest run, while allLines holds an older run or a comparison
+	shownRun        *run                       // the older run displayed, or the newer of the compared runs
+	comparedRun     *run                       // the older of the compared runs, nil unless comparing
+	diffAdded       int                        // number of lines added by the compared run
+	diffRemoved     int                        // number of lines removed by the compared run
+	running         bool                       // whether the command is currently running
+	exitStatus      *mediator.ExitStatus       // how the last run ended, if it did
+	inSteps         bool                       // whether the latest run is in its pre-steps, or they failed, the previous run going on
+	previousRunning bool                       // whether the previous run was going on when the latest one began
+	pendingRestart  *mediator.ScheduledRestart // automatic restart waiting for its delay
+	partialLines    map[string]string          // merged pane only: incomplete last line of each source
+	sourceStates    map[string][]string        // merged pane only: SGR sequences in effect for each source
+}
+
+func newPane(source Source, scrollback int) *pane {
+	return &pane{
+		name:        source.Name,
+		command:     source.Command,
+		mediator:    source.Mediator,
+		input:       source.Input,
+		allLines:    newScrollback(scrollback),
+		activeMatch: -1,
+		activeError: -1,
+	}
+}
+
+// A pane showing the output of every source, each line prefixed
+// with the name of its source
+func newMergedPane(sources []Source, scrollback int) *pane {
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.Name
+	}
+
+	return &pane{
+		command:      strings.Join(names, ", "),
+		allLines:     newScrollback(scrollback),
+		activeMatch:  -1,
+		activeError:  -1,
+		partialLines: make(map[string]string),
+		sourceStates: make(map[string][]string),
+	}
+}
+
+func (p *pane) isMerged() bool {
+	return p.partialLines != nil
+}
+
+// The command, like "go test ./...", or "stdin (EOF)" once the end of the
+// standard input was reached
+func (p *pane) description() string {
+	if p.closed {
+		return p.command + " (EOF)"
+	}
+
+	return p.command
+}
+
+func (p *pane) title() string {
+	if p.isMerged() {
+		return "all"
+	}
+	return p.name
+}
+
+// Appends content to the whole content, wrapped at width.
+// Returns the number of the first line that changed
+func (p *pane) appendContent(content string, width int) int {
+	var state []string
+	if p.output().len() > 0 {
+		state = p.output().last().prefix
+	}
+
+	from, _ := p.output().appendStyled(content, width, state)
+	return from
+}
+
+// Appends the complete lines of content from source, prefixed with its name.
+// An incomplete last line is kept until the rest of it comes in.
+// Returns the number of the first line that changed
+func (p *pane) appendFrom(source string, content string, width int) int {
+	lines := strings.Split(p.partialLines[source]+content, "\n")
+	p.partialLines[source] = lines[len(lines)-1]
+
+	builder := strings.Builder{}
+	for _, line := range lines[:len(lines)-1] {
+		builder.WriteString(fmt.Sprintf("[%s] %s\n", source, line))
+	}
+
+	if builder.Len() == 0 {
+		return p.allLines.end()
+	}
+
+	// the colors of a source do not bleed into the lines of the others
+	from, state := p.allLines.appendStyled(builder.String(), width, p.sourceStates[source])
+	p.sourceStates[source] = state
+
+	return from
+}
+
+// Appends the header of a run on a line of its own, from source for the
+// merged pane. Returns the number of the first line that changed, and that
+// of the header
+func (p *pane) appendRunHeader(source string, header string, width int) (int, int) {
+	lines := p.output()
+	content := header + "\n"
+
+	from := 0
+	if p.isMerged() {
+		if p.partialLines[source] != "" {
+			content = "\n" + content
+		}
+		from = p.appendFrom(source, content, width)
+	} else {
+		if lines.len() > 0 && lines.last().raw != "" {
+			content = "\n" + content
+		}
+		from = p.appendContent(content, width)
+	}
+
+	// the header is followed by an empty line, the next one to be continued
+	n := lines.head(lines.end() - 2)
+	line := lines.at(n)
+	line.runHeader = true
+	lines.set(n, line)
+
+	return from, n
+}
+
+// Replaces the whole content, without wrapping it
+func (p *pane) setContent(content string) {
+	p.allLines = newScrollback(p.allLines.max)
+
+	var state []string
+	for _, raw := range strings.Split(content, "\n") {
+		line := parseLine(raw, state)
+		state = line.endState()
+		p.allLines.append(line)
+	}
+	p.allLines.parseLogLines(p.allLines.first(), 0)
+}
+
+func (p *pane) clear() {
+	p.runStart = 0
+	// the output of the latest run starts over
+	if latest := p.latestRun(); latest != nil {
+		latest.start = 0
+	}
+	// the older run or the comparison stays displayed
+	if p.isBrowsing() {
+		p.live = newScrollback(p.live.max)
+		return
+	}
+
+	p.allLines = newScrollback(p.allLines.max)
+	p.matchedLines = []int{}
+	p.filteredIndices = []int{}
+	p.hunkStarts = []int{}
+	p.renderedLines = []string{}
+	p.searchResults = []searchMatch{}
+	p.scrollPos = 0
+	p.activeError = -1
+}
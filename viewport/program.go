@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
@@ -11,33 +13,104 @@ import (
 )
 
 type Program struct {
-	prog     *tea.Program
-	mediator mediator.Mediator
+	prog *tea.Program
 }
 
-func NewProgram(command string, mediator mediator.Mediator) *Program {
-	model := NewModel(command, mediator)
+// Settings of the user interface
+type Options struct {
+	KeyMap     KeyMap   // key bindings
+	Filters    []string // saved filter patterns, recalled while filtering
+	Filter     string   // filter pattern applied from the start, if not empty
+	Scrollback int      // maximum number of lines kept by each pane, 0 for unlimited
+	Input      *os.File // where the keys are read from, when the standard input is a source
 
-	teaProgram := tea.NewProgram(
-		model,
+	ContextBefore int // number of lines displayed before each line passing the filter
+	ContextAfter  int // number of lines displayed after each line passing the filter
+
+	Structured bool   // whether structured log lines are displayed as "time level msg key=value"
+	ErrorFile  string // where the source locations reported by each run are written, if not empty
+	History    int    // number of runs kept by each pane, to be displayed or compared again, 0 for unlimited
+	Keep       bool   // whether the output of every run is kept, under a header, instead of being cleared
+
+	Replay Replayer // controls the replay of a recorded session, nil unless replaying
+}
+
+func DefaultOptions() Options {
+	return Options{
+		KeyMap:     DefaultKeyBinding(),
+		Scrollback: DefaultScrollback,
+		History:    DefaultHistory,
+	}
+}
+
+func NewProgram(options Options, sources ...Source) *Program {
+	model := NewModel(options, sources)
+
+	programOptions := []tea.ProgramOption{
 		tea.WithAltScreen(),       // use the full size of the terminal in its "alternate screen buffer"
 		tea.WithMouseCellMotion(), // turn on mouse support so we can track the mouse wheel
-	)
+	}
+	if options.Input != nil {
+		programOptions = append(programOptions, tea.WithInput(options.Input))
+	}
+
+	teaProgram := tea.NewProgram(model, programOptions...)
 
 	prog := &Program{
-		prog:     teaProgram,
-		mediator: mediator,
+		prog: teaProgram,
 	}
 
-	mediator.AddListener(prog)
+	for _, source := range sources {
+		source.Mediator.AddListener(&sourceListener{
+			send: teaProgram.Send,
+			pane: source.Name,
+			keep: options.Keep,
+		})
+	}
 
 	return prog
 }
 
+// Appends content to every pane
 func (p *Program) Append(content string) {
 	p.prog.Send(AppendContentMsg{Content: content})
 }
 
+// Shows the backend of the file watcher, its number of watched paths,
+// and how many events were ignored as the content of the file did not change
+func (p *Program) SetWatchStatus(backend string, watches int, suppressed int) {
+	p.prog.Send(WatchStatusMsg{Backend: backend, Watches: watches, Suppressed: suppressed})
+}
+
+// Replaces the filter stack of the pane of the named source, or of the
+// active pane when name is empty, with pattern
+func (p *Program) SetFilter(name string, pattern string) {
+	p.prog.Send(SetFilterMsg{Pane: name, Pattern: pattern})
+}
+
+// Searches pattern in the pane of the named source, or in the active pane
+// when name is empty
+func (p *Program) SetSearch(name string, pattern string) {
+	p.prog.Send(SetSearchMsg{Pane: name, Pattern: pattern})
+}
+
+// Forgets the output and the runs of every pane, before a recorded
+// session is replayed again from its start
+func (p *Program) Reset() {
+	p.prog.Send(ResetMsg{})
+}
+
+// Shows how far the replay of a recorded session went
+func (p *Program) SetReplayStatus(position, duration time.Duration, speed float64, paused, ended bool) {
+	p.prog.Send(ReplayStatusMsg{
+		Position: position,
+		Duration: duration,
+		Speed:    speed,
+		Paused:   paused,
+		Ended:    ended,
+	})
+}
+
 func (p *Program) Run() {
 	f, err := tea.LogToFile("monique.log", "debug")
 	if err != nil {
@@ -53,30 +126,75 @@ func (p *Program) Run() {
 	}
 }
 
+// Forwards the events of a source to its pane
+type sourceListener struct {
+	send    func(tea.Msg) // sends a message to the program
+	pane    string
+	keep    bool // whether the output of the previous runs is kept
+	mu      sync.Mutex
+	changes mediator.ChangeSet // files changed since the command last started
+}
+
 // MARK: MediatorListener
 
-func (p *Program) OnStart(command string) {
-	p.prog.Send(ClearContentMsg{})
-	p.prog.Send(
-		AppendContentMsg{Content: fmt.Sprintf("Starting %s\n", command)},
+func (l *sourceListener) OnStart(command string) {
+	l.mu.Lock()
+	changes := l.changes
+	l.changes = mediator.ChangeSet{}
+	l.mu.Unlock()
+
+	l.send(StartMsg{Pane: l.pane, Command: command, Changes: changes})
+	if !l.keep {
+		l.send(ClearContentMsg{Pane: l.pane})
+	}
+	l.send(
+		AppendContentMsg{Pane: l.pane, Content: fmt.Sprintf("Starting %s\n", command)},
 	)
 }
 
-func (p *Program) OnError(err error) {
-	p.prog.Send(AppendContentMsg{Content: fmt.Sprintf("Error: %s\n", err)})
+func (l *sourceListener) OnError(err error) {
+	l.send(AppendContentMsg{Pane: l.pane, Content: fmt.Sprintf("Error: %s\n", err)})
+}
+
+func (l *sourceListener) OnKill() {
+	l.send(AppendContentMsg{Pane: l.pane, Content: "Process killed\n"})
+}
+
+func (l *sourceListener) OnStop() {
+	l.send(OutputClosedMsg{Pane: l.pane})
+}
+
+func (l *sourceListener) OnExit(status mediator.ExitStatus) {
+	l.send(ExitMsg{Pane: l.pane, Status: status})
+	l.send(AppendContentMsg{Pane: l.pane, Content: runSeparator(status) + "\n"})
+}
+
+func (l *sourceListener) OnStep(step mediator.Step) {
+	l.send(StepMsg{Pane: l.pane, Step: step})
+	l.send(AppendContentMsg{Pane: l.pane, Content: stepHeader(step) + "\n"})
+}
+
+func (l *sourceListener) OnOutput(output string) {
+	l.send(AppendContentMsg{Pane: l.pane, Content: output})
+}
+
+func (l *sourceListener) OnRequestRestart() {
 }
 
-func (p *Program) OnKill() {
-	p.prog.Send(AppendContentMsg{Content: "Killing process\n"})
+func (l *sourceListener) OnRequestStop() {
 }
 
-func (p *Program) OnStop() {
-	p.prog.Send(AppendContentMsg{Content: "Process exited\n"})
+func (l *sourceListener) OnFilesChanged(changes mediator.ChangeSet) {
+	l.mu.Lock()
+	l.changes.Merge(changes)
+	l.mu.Unlock()
 }
 
-func (p *Program) OnOutput(output string) {
-	p.prog.Send(AppendContentMsg{Content: output})
+func (l *sourceListener) OnRestartScheduled(restart mediator.ScheduledRestart) {
+	l.send(RestartScheduledMsg{Pane: l.pane, Restart: restart})
 }
 
-func (p *Program) OnRequestRestart() {
+func (l *sourceListener) OnCancelRestart() {
+	// may be sent from the update loop itself
+	go l.send(RestartCanceledMsg{Pane: l.pane})
 }
@@ -0,0 +1,63 @@
+package viewport
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/gaelph/monique/mediator"
+	"github.com/gaelph/monique/runner"
+)
+
+// The text of the lines of the pane, one per line
+func paneText(p *pane) string {
+	lines := p.output()
+	texts := make([]string, 0, lines.len())
+	for n := lines.first(); n < lines.end(); n++ {
+		texts = append(texts, lines.at(n).text)
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+func TestPreStepOutputStaysAfterCommandStarts(t *testing.T) {
+	m := newTestModel(DefaultScrollback)
+
+	var (
+		mu       sync.Mutex
+		messages []tea.Msg
+	)
+	med := mediator.NewMediator()
+	med.AddListener(&sourceListener{
+		send: func(msg tea.Msg) {
+			mu.Lock()
+			messages = append(messages, msg)
+			mu.Unlock()
+		},
+		pane: m.pane.name,
+	})
+
+	r := runner.NewRunner([]string{"echo", "app up"}, 0)
+	r.SetPreSteps([][]string{{"echo", "building"}})
+	r.SetMediator(med)
+	r.Start()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, msg := range messages {
+		updated, _ := m.Update(msg)
+		*m = updated.(model)
+	}
+
+	text := paneText(m.pane)
+	building := strings.Index(text, "\nbuilding\n")
+	app := strings.Index(text, "\napp up\n")
+	if building < 0 || app < 0 || building > app {
+		t.Fatalf("pane holds %q, want the output of the pre-step, then that of the command", text)
+	}
+	if len(m.runs) != 1 {
+		t.Errorf("%d runs, want 1", len(m.runs))
+	}
+}
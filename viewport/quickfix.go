@@ -0,0 +1,78 @@
+package viewport
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Where the error locations are written, when no path is given with -errorfile:
+// the default errorfile of vim
+const defaultErrorFile = "errors.err"
+
+// The error locations were written to an error file
+type errorsExportedMsg struct {
+	path  string
+	count int
+	err   error
+}
+
+// The source locations reported by the latest run of the command of
+// every pane
+func (m model) runLocations() []*location {
+	locations := []*location{}
+	for _, p := range m.panes {
+		if p.isMerged() {
+			continue
+		}
+
+		lines := p.output()
+		for n := max(p.runStart, lines.first()); n < lines.end(); n++ {
+			line := lines.at(n)
+			if !line.continued && line.location != nil {
+				locations = append(locations, line.location)
+			}
+		}
+	}
+
+	return locations
+}
+
+// Writes the source locations of the latest run to path, in the
+// "file:line:col: message" format of the quickfix list of vim and neovim,
+// or "file:line:col" when the message is not known
+func (m model) exportErrors(path string) tea.Cmd {
+	if path == "" {
+		path = defaultErrorFile
+	}
+	locations := m.runLocations()
+
+	return func() tea.Msg {
+		builder := strings.Builder{}
+		for _, loc := range locations {
+			if loc.message == "" {
+				builder.WriteString(fmt.Sprintf("%s\n", loc))
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("%s: %s\n", loc, loc.message))
+		}
+
+		err := os.WriteFile(path, []byte(builder.String()), 0o644)
+		return errorsExportedMsg{path: path, count: len(locations), err: err}
+	}
+}
+
+// Like "3 errors written to errors.err"
+func (msg errorsExportedMsg) String() string {
+	if msg.err != nil {
+		return fmt.Sprintf("could not write the errors: %s", msg.err)
+	}
+
+	noun := "errors"
+	if msg.count == 1 {
+		noun = "error"
+	}
+	return fmt.Sprintf("%d %s written to %s", msg.count, noun, msg.path)
+}
@@ -0,0 +1,31 @@
+package viewport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportErrors(t *testing.T) {
+	m := newTestModel(DefaultScrollback)
+	m.allLines.appendStyled("# app\nmain.go:12:3: undefined: x\nmain.go:14\nok\n", m.contentWidth(), nil)
+	m.refresh()
+
+	path := filepath.Join(t.TempDir(), "errors.err")
+	msg := m.exportErrors(path)().(errorsExportedMsg)
+	if msg.err != nil {
+		t.Fatal(msg.err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "main.go:12:3: undefined: x\nmain.go:14\n"
+	if string(content) != want {
+		t.Errorf("wrote %q, want %q", content, want)
+	}
+	if msg.count != 2 {
+		t.Errorf("%d errors written, want 2", msg.count)
+	}
+}
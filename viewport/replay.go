@@ -0,0 +1,116 @@
+package viewport
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// How far the seek keys move a replay
+const seekStep = 10 * time.Second
+
+// Controls the replay of a recorded session
+type Replayer interface {
+	TogglePause()
+	Seek(offset time.Duration)
+}
+
+// How far the replay of a recorded session went
+type ReplayStatusMsg struct {
+	Position time.Duration // time of the session replayed so far
+	Duration time.Duration // of the whole session
+	Speed    float64
+	Paused   bool
+	Ended    bool // whether the whole session was replayed
+}
+
+// Forget the output and the runs of every pane, before a recorded
+// session is replayed again from its start
+type ResetMsg struct{}
+
+// Forgets the output and the runs of the pane, keeping its filter and search
+func (p *pane) reset() {
+	fresh := &pane{
+		name:         p.name,
+		mediator:     p.mediator,
+		command:      p.command,
+		input:        p.input,
+		searchString: p.searchString,
+		filterString: p.filterString,
+		filters:      p.filters,
+		allLines:     newScrollback(p.output().max),
+		activeMatch:  -1,
+		activeError:  -1,
+	}
+	if p.isMerged() {
+		fresh.partialLines = make(map[string]string)
+		fresh.sourceStates = make(map[string][]string)
+	}
+
+	*p = *fresh
+}
+
+func (m model) reset() model {
+	for _, p := range m.panes {
+		p.reset()
+	}
+	m.showingRuns = false
+	m.markedRun = nil
+	m.pendingContent = nil
+	m.refresh()
+
+	return m
+}
+
+// Seeks the replay by offset, out of the update loop, which receives
+// the events replayed meanwhile
+func (m model) seek(offset time.Duration) tea.Cmd {
+	replay := m.replay
+	return func() tea.Msg {
+		replay.Seek(offset)
+		return nil
+	}
+}
+
+func (m model) togglePause() tea.Cmd {
+	replay := m.replay
+	return func() tea.Msg {
+		replay.TogglePause()
+		return nil
+	}
+}
+
+// Like "Replay ▶ 0:12 / 1:03 · 4x"
+func (m model) replayStatusView() string {
+	status := m.replayStatus
+	if status == nil {
+		return ""
+	}
+
+	state := "▶"
+	switch {
+	case status.Ended:
+		state = "■"
+	case status.Paused:
+		state = "⏸"
+	}
+
+	view := fmt.Sprintf("Replay %s %s / %s", state, formatClock(status.Position), formatClock(status.Duration))
+	if status.Speed != 1 {
+		view += " · " + strconv.FormatFloat(status.Speed, 'f', -1, 64) + "x"
+	}
+
+	return view
+}
+
+// Like "1:03", or "1:02:03"
+func formatClock(d time.Duration) string {
+	seconds := int(d / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,149 @@
+package viewport
+
+import (
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Scrolls through the rendered rows of the content.
+// Works like the bubbles viewport, but takes the rows as a slice, so that
+// appending lines does not join and split the whole content again
+type rowViewport struct {
+	Width   int
+	Height  int
+	YOffset int // index of the first visible row
+
+	keyMap     viewport.KeyMap
+	wheelDelta int // number of rows scrolled by the mouse wheel
+	rows       []string
+}
+
+func newRowViewport(width, height int) rowViewport {
+	return rowViewport{
+		Width:      width,
+		Height:     height,
+		keyMap:     viewport.DefaultKeyMap(),
+		wheelDelta: 3,
+	}
+}
+
+// Sets the rows displayed. They are not copied, and must not be changed
+// until they are set again
+func (v *rowViewport) SetRows(rows []string) {
+	v.rows = rows
+	if v.YOffset > len(v.rows)-1 {
+		v.GotoBottom()
+	}
+}
+
+func (v *rowViewport) SetContent(content string) {
+	v.SetRows(strings.Split(content, "\n"))
+}
+
+func (v rowViewport) TotalLineCount() int {
+	return len(v.rows)
+}
+
+func (v rowViewport) maxYOffset() int {
+	return max(0, len(v.rows)-v.Height)
+}
+
+func (v rowViewport) AtTop() bool {
+	return v.YOffset <= 0
+}
+
+func (v rowViewport) AtBottom() bool {
+	return v.YOffset >= v.maxYOffset()
+}
+
+// How far the content is scrolled, between 0 and 1
+func (v rowViewport) ScrollPercent() float64 {
+	if v.Height >= len(v.rows) {
+		return 1.0
+	}
+	y := float64(v.YOffset)
+	h := float64(v.Height)
+	t := float64(len(v.rows) - 1)
+
+	return math.Max(0.0, math.Min(1.0, y/(t-h)))
+}
+
+func (v *rowViewport) SetYOffset(n int) {
+	v.YOffset = min(max(n, 0), v.maxYOffset())
+}
+
+func (v *rowViewport) LineDown(n int) {
+	v.SetYOffset(v.YOffset + n)
+}
+
+func (v *rowViewport) LineUp(n int) {
+	v.SetYOffset(v.YOffset - n)
+}
+
+func (v *rowViewport) HalfViewDown() {
+	v.LineDown(v.Height / 2)
+}
+
+func (v *rowViewport) HalfViewUp() {
+	v.LineUp(v.Height / 2)
+}
+
+func (v *rowViewport) GotoTop() {
+	v.SetYOffset(0)
+}
+
+func (v *rowViewport) GotoBottom() {
+	v.SetYOffset(v.maxYOffset())
+}
+
+// Scrolls with the keys of the bubbles viewport, and the mouse wheel
+func (v rowViewport) Update(msg tea.Msg) (rowViewport, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, v.keyMap.PageDown):
+			v.LineDown(v.Height)
+		case key.Matches(msg, v.keyMap.PageUp):
+			v.LineUp(v.Height)
+		case key.Matches(msg, v.keyMap.HalfPageDown):
+			v.HalfViewDown()
+		case key.Matches(msg, v.keyMap.HalfPageUp):
+			v.HalfViewUp()
+		case key.Matches(msg, v.keyMap.Down):
+			v.LineDown(1)
+		case key.Matches(msg, v.keyMap.Up):
+			v.LineUp(1)
+		}
+
+	case tea.MouseMsg:
+		if msg.Action != tea.MouseActionPress {
+			break
+		}
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			v.LineUp(v.wheelDelta)
+		case tea.MouseButtonWheelDown:
+			v.LineDown(v.wheelDelta)
+		}
+	}
+
+	return v, nil
+}
+
+// The visible rows, padded to the size of the viewport
+func (v rowViewport) View() string {
+	top := min(max(v.YOffset, 0), len(v.rows))
+	bottom := min(top+v.Height, len(v.rows))
+
+	return lipgloss.NewStyle().
+		Width(v.Width).
+		Height(v.Height).
+		MaxHeight(v.Height).
+		MaxWidth(v.Width).
+		Render(strings.Join(v.rows[top:bottom], "\n"))
+}
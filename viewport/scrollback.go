@@ -0,0 +1,148 @@
+package viewport
+
+import (
+	"strings"
+
+	"github.com/muesli/reflow/wrap"
+)
+
+// Default maximum number of lines kept by each pane
+const DefaultScrollback = 100000
+
+// Lines of a pane, in a ring buffer dropping the oldest ones once full.
+// Lines are numbered from the first one ever appended, so that the numbers
+// of the lines kept do not change when older ones are dropped
+type scrollback struct {
+	lines   []styledLine
+	start   int // index in lines of the oldest line
+	dropped int // number of lines dropped so far, that is the number of the oldest line
+	max     int // maximum number of lines, 0 for unlimited
+}
+
+func newScrollback(max int) *scrollback {
+	return &scrollback{max: max}
+}
+
+// Number of the oldest line kept
+func (s *scrollback) first() int {
+	return s.dropped
+}
+
+// Number of the line after the newest one
+func (s *scrollback) end() int {
+	return s.dropped + len(s.lines)
+}
+
+func (s *scrollback) len() int {
+	return len(s.lines)
+}
+
+// The line numbered n, which must be between first() and end()
+func (s *scrollback) at(n int) styledLine {
+	return s.lines[(s.start+n-s.dropped)%len(s.lines)]
+}
+
+func (s *scrollback) last() styledLine {
+	return s.at(s.end() - 1)
+}
+
+func (s *scrollback) setLast(line styledLine) {
+	s.lines[(s.start+len(s.lines)-1)%len(s.lines)] = line
+}
+
+// Appends a line, dropping the oldest one when full
+func (s *scrollback) append(line styledLine) {
+	if s.max <= 0 || len(s.lines) < s.max {
+		s.lines = append(s.lines, line)
+		return
+	}
+
+	s.lines[s.start] = line
+	s.start = (s.start + 1) % len(s.lines)
+	s.dropped++
+}
+
+// Replaces the line numbered n, which must be between first() and end()
+func (s *scrollback) set(n int, line styledLine) {
+	s.lines[(s.start+n-s.dropped)%len(s.lines)] = line
+}
+
+// Number of the first line kept of the output line that the line
+// numbered n is part of, once wrapped
+func (s *scrollback) head(n int) int {
+	for n > s.first() && s.at(n).continued {
+		n--
+	}
+
+	return n
+}
+
+// Appends content, wrapped at width, the first of its lines continuing the
+// last line. state holds the SGR sequences in effect before content.
+// Returns the number of the first line that changed, and the SGR sequences
+// in effect at the end of content
+func (s *scrollback) appendStyled(content string, width int, state []string) (int, []string) {
+	from := s.end()
+	if s.len() > 0 {
+		from--
+	}
+
+	for i, text := range strings.Split(content, "\n") {
+		// the last line is wrapped again along with the rest of it
+		continues := i == 0 && s.len() > 0
+		continued := false
+		if continues {
+			text = s.last().raw + text
+			continued = s.last().continued
+		}
+
+		for j, raw := range strings.Split(wrapLine(text, width), "\n") {
+			line := parseLine(raw, state)
+			line.continued = continued || j > 0
+			state = line.endState()
+
+			if continues && j == 0 {
+				s.setLast(line)
+			} else {
+				s.append(line)
+			}
+		}
+	}
+
+	return s.parseLogLines(from, width), state
+}
+
+// Parses the structured log lines, tells the level of the output lines,
+// and finds the source locations they report, from the line numbered from, which may be one of the wrapped
+// lines of an output line.
+// Returns the number of the first line whose record, level or location changed
+func (s *scrollback) parseLogLines(from int, width int) int {
+	from = s.head(max(from, s.first()))
+	for n := from; n < s.end(); {
+		text := strings.Builder{}
+		end := n
+		for end < s.end() && (end == n || s.at(end).continued) {
+			text.WriteString(s.at(end).text)
+			end++
+		}
+
+		line := s.at(n)
+		line.record = parseRecord(text.String(), width)
+		line.level = detectLevel(text.String(), line.record)
+		line.location = parseLocation(s, n, text.String())
+		s.set(n, line)
+		n = end
+	}
+
+	return from
+}
+
+// Wraps text at width, keeping every character so that wrapped
+// lines can be joined back together
+func wrapLine(text string, width int) string {
+	writer := wrap.NewWriter(width)
+	writer.PreserveSpace = true
+	writer.Write([]byte(text))
+
+	return writer.String()
+}
@@ -0,0 +1,190 @@
+package viewport
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/gaelph/monique/filter"
+	"github.com/gaelph/monique/mediator"
+)
+
+// MARK: - Ring buffer
+
+func TestScrollbackWrapsAroundAtCap(t *testing.T) {
+	s := newScrollback(3)
+	for i := 0; i < 5; i++ {
+		s.append(styledLine{raw: fmt.Sprint(i)})
+	}
+
+	if s.len() != 3 {
+		t.Fatalf("len() = %d, want 3", s.len())
+	}
+	if s.first() != 2 || s.end() != 5 {
+		t.Fatalf("lines %d to %d, want 2 to 5", s.first(), s.end())
+	}
+	for n := s.first(); n < s.end(); n++ {
+		if got := s.at(n).raw; got != fmt.Sprint(n) {
+			t.Errorf("at(%d) = %q, want %q", n, got, fmt.Sprint(n))
+		}
+	}
+	if got := s.last().raw; got != "4" {
+		t.Errorf("last() = %q, want \"4\"", got)
+	}
+}
+
+func TestScrollbackNumbersLinesAfterEviction(t *testing.T) {
+	s := newScrollback(4)
+	from, _ := s.appendStyled("a\nb\nc\n", 80, nil)
+	if from != 0 {
+		t.Fatalf("first changed line = %d, want 0", from)
+	}
+
+	// "d" continues the empty last line, numbered 3
+	from, _ = s.appendStyled("d\ne\nf", 80, nil)
+	if from != 3 {
+		t.Fatalf("first changed line = %d, want 3", from)
+	}
+	if s.first() != 2 || s.end() != 6 {
+		t.Fatalf("lines %d to %d, want 2 to 6", s.first(), s.end())
+	}
+
+	want := []string{"c", "d", "e", "f"}
+	for i, text := range want {
+		n := s.first() + i
+		if got := s.at(n).text; got != text {
+			t.Errorf("at(%d) = %q, want %q", n, got, text)
+		}
+	}
+
+	// the last line is wrapped again along with the rest of it, in place
+	from, _ = s.appendStyled("g\n", 80, nil)
+	if from != 5 || s.at(5).text != "fg" {
+		t.Errorf("line 5 = %q from %d, want \"fg\" from 5", s.at(5).text, from)
+	}
+	if s.first() != 3 || s.at(3).text != "d" {
+		t.Errorf("oldest line %d = %q, want 3 = \"d\"", s.first(), s.at(s.first()).text)
+	}
+}
+
+func TestScrollbackHeadAfterEviction(t *testing.T) {
+	s := newScrollback(3)
+	// wrapped in four lines, the first of which is dropped
+	s.appendStyled(strings.Repeat("x", 40), 10, nil)
+
+	if s.first() != 1 {
+		t.Fatalf("first() = %d, want 1", s.first())
+	}
+	if got := s.head(s.end() - 1); got != s.first() {
+		t.Errorf("head() = %d, want the oldest line kept, %d", got, s.first())
+	}
+}
+
+func TestRefreshFromKeepsMatchesOfEvictedLines(t *testing.T) {
+	m := newTestModel(4)
+	m.filters = []filter.Term{filter.Parse("even")}
+	m.refresh()
+
+	for i := 0; i < 10; i++ {
+		parity := "odd"
+		if i%2 == 0 {
+			parity = "even"
+		}
+		from, _ := m.allLines.appendStyled(fmt.Sprintf("%d %s\n", i, parity), m.contentWidth(), nil)
+		m.refreshFrom(from)
+	}
+
+	// lines 7 to 10 are kept, the last one being empty
+	want := []int{8}
+	if fmt.Sprint(m.matchedLines) != fmt.Sprint(want) {
+		t.Errorf("matched lines %v, want %v", m.matchedLines, want)
+	}
+	if len(m.renderedLines) != len(m.filteredIndices) {
+		t.Errorf("%d rendered lines for %d displayed ones", len(m.renderedLines), len(m.filteredIndices))
+	}
+}
+
+func TestRefreshFromRendersLikeRefresh(t *testing.T) {
+	m := newTestModel(5)
+	m.searchString = "even"
+	m.refresh()
+
+	for i := 0; i < 12; i++ {
+		parity := "odd"
+		if i%2 == 0 {
+			parity = "even"
+		}
+		from, _ := m.allLines.appendStyled(fmt.Sprintf("%d %s\n", i, parity), m.contentWidth(), nil)
+		m.refreshFrom(from)
+
+		incremental := fmt.Sprint(m.renderedLines)
+		m.refresh()
+		if whole := fmt.Sprint(m.renderedLines); incremental != whole {
+			t.Fatalf("after line %d, rendered %q, want %q", i, incremental, whole)
+		}
+	}
+}
+
+// A ready model of a single pane, keeping scrollback lines
+func newTestModel(scrollback int) *model {
+	options := DefaultOptions()
+	options.Scrollback = scrollback
+	m := NewModel(options, []Source{{Name: "test", Command: "test", Mediator: mediator.NewMediator()}})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	m = updated.(model)
+
+	return &m
+}
+
+// MARK: - Benchmarks
+
+// A model whose active pane already holds history lines, its scrollback
+// being full so that every append drops as many lines
+func benchmarkModel(history int) *model {
+	m := newTestModel(history)
+
+	var content strings.Builder
+	for i := 0; i < history; i++ {
+		fmt.Fprintf(&content, "2024-01-01 12:00:00 INFO request %d served in 12ms\n", i)
+	}
+	m.allLines.appendStyled(content.String(), m.contentWidth(), nil)
+	m.refresh()
+
+	return m
+}
+
+var benchmarkHistories = []int{1000, 10000, 100000}
+
+func BenchmarkAppendStyled(b *testing.B) {
+	for _, history := range benchmarkHistories {
+		b.Run(fmt.Sprintf("history=%d", history), func(b *testing.B) {
+			m := benchmarkModel(history)
+			width := m.contentWidth()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.allLines.appendStyled("2024-01-01 12:00:01 WARN slow request\n", width, nil)
+			}
+		})
+	}
+}
+
+func BenchmarkRefreshFrom(b *testing.B) {
+	for _, history := range benchmarkHistories {
+		b.Run(fmt.Sprintf("history=%d", history), func(b *testing.B) {
+			m := benchmarkModel(history)
+			m.filters = []filter.Term{filter.Parse("request"), filter.Parse("!health")}
+			m.searchString = "served|slow"
+			m.refresh()
+			width := m.contentWidth()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				from, _ := m.allLines.appendStyled("2024-01-01 12:00:01 WARN slow request\n", width, nil)
+				m.refreshFrom(from)
+			}
+		})
+	}
+}
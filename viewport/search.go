@@ -1,88 +1,86 @@
 package viewport
 
 import (
-	"regexp"
-	"strings"
-	"unicode"
+	"sort"
+
+	"github.com/gaelph/monique/filter"
 )
 
 type searchMatch struct {
 	text  string // The text that matched
-	id    int    // An identifier for the match
+	id    int    // An identifier for the match, increasing with line and column
 	line  int    // The line in whole content where the match was found
 	start int    // Start column of the match
 	end   int    // End column of the match
 }
 
-func shouldCaseSensitive(pattern string) bool {
-	for _, r := range pattern {
-		if unicode.IsUpper(r) {
-			return true
-		}
-	}
-
-	return false
-}
+func (m model) search(lines *scrollback, indices []int) ([]searchMatch, int) {
+	searchResults := m.searchLines(lines, indices, []searchMatch{})
 
-func makeInsensitive(pattern string) string {
-	return "(?i)" + pattern
-}
+	nextActiveMatch := m.activeMatch
+	if (nextActiveMatch == -1 && len(searchResults) > 0) ||
+		nextActiveMatch > len(searchResults)-1 {
+		nextActiveMatch = len(searchResults) - 1
+	}
 
-func addTopLevelCapture(pattern string) string {
-	return "(" + pattern + ")"
+	return searchResults, nextActiveMatch
 }
 
-func (m model) search(lines []string, indices []int) ([]searchMatch, int) {
+// Appends the matches of the search string in the lines at indices
+// to searchResults
+func (m model) searchLines(
+	lines *scrollback,
+	indices []int,
+	searchResults []searchMatch,
+) []searchMatch {
 	if m.searchString == "" {
-		return []searchMatch{}, -1
-	}
-
-	pattern := addTopLevelCapture(m.searchString)
-	if !shouldCaseSensitive(pattern) {
-		pattern = makeInsensitive(pattern)
+		return searchResults
 	}
 
-	reg, err := regexp.Compile(pattern)
+	reg, err := filter.CompilePattern(m.searchString)
 	if err != nil {
-		return []searchMatch{}, -1
+		return searchResults
 	}
 
-	searchResults := make([]searchMatch, 0)
+	// ids are never reused, as matches are dropped along with their line
+	id := 0
+	if len(searchResults) > 0 {
+		id = searchResults[len(searchResults)-1].id + 1
+	}
 
 	for _, lineNr := range indices {
-		line := lines[lineNr]
+		line := m.displayLine(lines.at(lineNr)).text
 		locations := reg.FindAllStringIndex(line, -1)
 		for _, location := range locations {
 			searchResult := searchMatch{
-				id:    len(searchResults),
+				id:    id,
 				line:  lineNr,
 				start: location[0],
 				end:   location[1],
 				text:  line[location[0]:location[1]],
 			}
 			searchResults = append(searchResults, searchResult)
+			id++
 		}
 	}
 
-	nextActiveMatch := m.activeMatch
-	if (nextActiveMatch == -1 && len(searchResults) > 0) ||
-		nextActiveMatch > len(searchResults)-1 {
-		nextActiveMatch = len(searchResults) - 1
-	}
-
-	return searchResults, nextActiveMatch
+	return searchResults
 }
 
-func (m model) searchResultsAtLine(lineNr int) []searchMatch {
-	searchResults := make([]searchMatch, 0)
+// Index of the first search result at or after the line numbered lineNr
+func (m model) searchResultsFrom(lineNr int) int {
+	return sort.Search(len(m.searchResults), func(i int) bool {
+		return m.searchResults[i].line >= lineNr
+	})
+}
 
-	for _, match := range m.searchResults {
-		if match.line == lineNr {
-			searchResults = append(searchResults, match)
-		}
+// The id of the active search match, -1 if none
+func (m model) activeMatchID() int {
+	if m.activeMatch < 0 || m.activeMatch >= len(m.searchResults) {
+		return -1
 	}
 
-	return searchResults
+	return m.searchResults[m.activeMatch].id
 }
 
 func (m model) hasSearchResults() bool {
@@ -90,34 +88,12 @@ func (m model) hasSearchResults() bool {
 }
 
 func decorateLine(
-	line string,
+	line styledLine,
 	searchResults []searchMatch,
-	activeMatch int,
+	activeMatchID int,
 	lineNr, maxLine int,
 ) string {
-	offsets := make(map[int]int)
-	for _, searchResult := range searchResults {
-		builder := strings.Builder{}
-		offset := offsets[searchResult.line]
-		start := searchResult.start + offset
-		end := searchResult.end + offset
-
-		// from 0 or end of previous match
-		builder.WriteString(line[0:start])
-		// match
-		if activeMatch >= 0 && activeMatch == searchResult.id {
-			styled := activeMatchStyle.Render(searchResult.text)
-			builder.WriteString(styled)
-		} else {
-			styled := searchMatchStyle.Render(searchResult.text)
-			builder.WriteString(styled)
-		}
-		builder.WriteString(line[end:])
-		newLine := builder.String()
-		offset = len(newLine) - len(line)
-		offsets[searchResult.line] += offset
-		line = newLine
-	}
+	decorated := line.render(searchResults, activeMatchID)
 
 	// This add line numbers. Should this be an option ?
 	// lineWidth := len(strconv.Itoa(maxLine))
@@ -132,9 +108,9 @@ func decorateLine(
 	// 	PaddingRight(2).
 	// 	Render(lineNrStr)
 	//
-	// return lineNrStr + line
+	// return lineNrStr + decorated
 
-	return line
+	return decorated
 }
 
 func (m *model) getActiveMatchLine() int {
@@ -150,15 +126,12 @@ func (m *model) getActiveMatchLine() int {
 
 	actualLine := m.searchResults[m.activeMatch].line
 
-	lineInBuffer := actualLine
-	for i := 0; i < len(m.filteredIndices); i++ {
-		if m.filteredIndices[i] == actualLine {
-			lineInBuffer = i
-			break
-		}
+	lineInBuffer := sort.SearchInts(m.filteredIndices, actualLine)
+	if lineInBuffer == len(m.filteredIndices) || m.filteredIndices[lineInBuffer] != actualLine {
+		return actualLine
 	}
 
-	return lineInBuffer
+	return m.rowOf(lineInBuffer)
 }
 
 func (m *model) getNextActiveMatch() int {
@@ -0,0 +1,76 @@
+package viewport
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+// Line appended to the output once a run ended, like:
+// ── exited with code 1 · 2.31s · user 1.2s · sys 120ms · max rss 24.0MB ──
+func runSeparator(status mediator.ExitStatus) string {
+	parts := []string{
+		status.String(),
+		formatDuration(status.Duration),
+	}
+	if status.UserTime > 0 || status.SystemTime > 0 {
+		parts = append(parts,
+			"user "+formatDuration(status.UserTime),
+			"sys "+formatDuration(status.SystemTime),
+		)
+	}
+	if status.MaxRSS > 0 {
+		parts = append(parts, "max rss "+formatKilobytes(status.MaxRSS))
+	}
+
+	line := fmt.Sprintf("── %s ──", strings.Join(parts, " · "))
+	if status.Success() {
+		return successSeparatorStyle.Render(line)
+	}
+	return failureSeparatorStyle.Render(line)
+}
+
+// Line appended to the output before each run, when the output of every
+// run is kept, like:
+// ── run #7 · 14:02:11 · triggered by api/handler.go ──
+func runHeader(r *run) string {
+	parts := []string{fmt.Sprintf("run #%d", r.number), r.startTime()}
+	if trigger := r.trigger(); trigger != "" {
+		parts = append(parts, "triggered by "+trigger)
+	}
+
+	return runHeaderStyle.Render(fmt.Sprintf("── %s ──", strings.Join(parts, " · ")))
+}
+
+// Line appended to the output before each step of a pipeline, like:
+// ── step 1/2 · go build -o bin/app . ──
+func stepHeader(step mediator.Step) string {
+	return stepHeaderStyle.Render(
+		fmt.Sprintf("── step %d/%d · %s ──", step.Index, step.Total, step.Command),
+	)
+}
+
+// Rounds durations to a readable precision
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Minute:
+		return d.Round(time.Second).String()
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	default:
+		return d.Round(time.Millisecond).String()
+	}
+}
+
+func formatKilobytes(kb int64) string {
+	switch {
+	case kb >= 1024*1024:
+		return fmt.Sprintf("%.1fGB", float64(kb)/(1024*1024))
+	case kb >= 1024:
+		return fmt.Sprintf("%.1fMB", float64(kb)/1024)
+	default:
+		return fmt.Sprintf("%dKB", kb)
+	}
+}
@@ -0,0 +1,304 @@
+package viewport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+)
+
+// A field of a structured log line, nested JSON keys joined with dots
+type field struct {
+	key   string
+	value string
+}
+
+// A structured log line, parsed from JSON or logfmt
+type record struct {
+	prefix  string     // like "[api] " in the merged pane, displayed before the record
+	time    string     // as found in the line
+	level   string     // as found in the line
+	msg     string     // the message
+	fields  []field    // the other fields, in order
+	display styledLine // the record, as displayed in the structured view
+}
+
+// Keys of the common fields, as written by zap, slog, logrus, bunyan and the like
+var (
+	timeKeys  = []string{"time", "ts", "timestamp", "@timestamp", "t"}
+	levelKeys = []string{"level", "lvl", "severity", "@level", "loglevel"}
+	msgKeys   = []string{"msg", "message", "@message"}
+)
+
+// Like "[api] ", as prefixed to the lines of the merged pane
+var sourcePrefix = regexp.MustCompile(`^\[[^\]]+\] `)
+
+// Parses a JSON or logfmt line, nil if it is neither.
+// The record is displayed on a single line, truncated at width
+func parseRecord(text string, width int) *record {
+	prefix := sourcePrefix.FindString(text)
+	body := strings.TrimSpace(text[len(prefix):])
+
+	var fields []field
+	switch {
+	case strings.HasPrefix(body, "{"):
+		fields = parseJSONFields(body)
+	case strings.Contains(body, "="):
+		fields = parseLogfmtFields(body)
+	}
+	if fields == nil {
+		return nil
+	}
+
+	rec := &record{prefix: prefix}
+	for _, f := range fields {
+		switch {
+		case rec.time == "" && isOneOf(f.key, timeKeys):
+			rec.time = f.value
+		case rec.level == "" && isOneOf(f.key, levelKeys):
+			rec.level = f.value
+		case rec.msg == "" && isOneOf(f.key, msgKeys):
+			rec.msg = f.value
+		default:
+			rec.fields = append(rec.fields, f)
+		}
+	}
+	rec.display = rec.render(width)
+
+	return rec
+}
+
+func isOneOf(key string, keys []string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(key, k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The value of the field named key, "time", "level" and "msg" standing
+// for the common fields, whatever their key in the line, and the other
+// way around
+func (r *record) get(key string) (string, bool) {
+	switch {
+	case r.time != "" && isOneOf(key, timeKeys):
+		return r.time, true
+	case r.level != "" && isOneOf(key, levelKeys):
+		return r.level, true
+	case r.msg != "" && isOneOf(key, msgKeys):
+		return r.msg, true
+	}
+
+	for _, f := range r.fields {
+		if f.key == key {
+			return f.value, true
+		}
+	}
+
+	return "", false
+}
+
+// Like "14:02:11.042 ERROR request failed status=500 path=/api"
+func (r *record) render(width int) styledLine {
+	parts := make([]string, 0, 3+len(r.fields))
+	if r.time != "" {
+		parts = append(parts, fieldKeyStyle.Render(formatTime(r.time)))
+	}
+	if r.level != "" {
+		label := strings.ToUpper(r.level)
+		parts = append(parts, levelStyle(r.level).Render(label+strings.Repeat(" ", max(5-len(label), 0))))
+	}
+	if r.msg != "" {
+		parts = append(parts, r.msg)
+	}
+	for _, f := range r.fields {
+		value := f.value
+		if value == "" || strings.ContainsAny(value, " \"=") {
+			value = strconv.Quote(value)
+		}
+		parts = append(parts, fieldKeyStyle.Render(f.key+"=")+value)
+	}
+
+	rendered := r.prefix + strings.Join(parts, " ")
+	if width > 0 && lipgloss.Width(rendered) > width {
+		rendered = truncate.StringWithTail(rendered, uint(width), "…")
+	}
+
+	return parseLine(rendered, nil)
+}
+
+// Times of the day, when the time is RFC 3339 or seconds since the epoch
+func formatTime(value string) string {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.Local().Format("15:04:05.000")
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		// milliseconds, as written by bunyan and pino
+		if seconds > 1e11 {
+			seconds /= 1000
+		}
+		whole, fraction := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(fraction*1e9)).Local().Format("15:04:05.000")
+	}
+
+	return value
+}
+
+// The line as displayed: its record in the structured view,
+// if it starts a structured log line
+func (m model) displayLine(line styledLine) styledLine {
+	if m.structured && line.record != nil {
+		return line.record.display
+	}
+
+	return line
+}
+
+// Whether the line is hidden, as it continues a structured log line,
+// which is displayed on a single line in the structured view.
+// rec is the record of the output line the line is part of
+func (m model) isHidden(line styledLine, rec *record) bool {
+	return m.structured && line.continued && rec != nil
+}
+
+// MARK: - JSON
+
+var errTrailingData = errors.New("unexpected data after the object")
+
+// The fields of a JSON object, in order, nil if line is not one
+func parseJSONFields(line string) []field {
+	fields := []field{}
+	if err := appendJSONObject([]byte(line), "", &fields); err != nil {
+		return nil
+	}
+
+	return fields
+}
+
+// Appends the fields of the JSON object in data to fields, their keys
+// prefixed with prefix
+func appendJSONObject(data []byte, prefix string, fields *[]field) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+
+		switch value[0] {
+		case '{':
+			if err := appendJSONObject(value, prefix+key+".", fields); err != nil {
+				return err
+			}
+		case '"':
+			var s string
+			if err := json.Unmarshal(value, &s); err != nil {
+				return err
+			}
+			*fields = append(*fields, field{key: prefix + key, value: s})
+		default:
+			compacted := bytes.Buffer{}
+			if err := json.Compact(&compacted, value); err != nil {
+				return err
+			}
+			*fields = append(*fields, field{key: prefix + key, value: compacted.String()})
+		}
+	}
+
+	// the closing brace, then nothing but spaces
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errTrailingData
+	}
+
+	return nil
+}
+
+// MARK: - logfmt
+
+// The fields of a logfmt line, like `level=info msg="started" port=8080`,
+// nil if line is not one
+func parseLogfmtFields(line string) []field {
+	fields := []field{}
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+
+		// key
+		start := i
+		for i < len(line) && isKeyByte(line[i]) {
+			i++
+		}
+		if i == start || i == len(line) || line[i] != '=' {
+			return nil
+		}
+		key := line[start:i]
+		i++
+
+		// value, quoted or up to the next space
+		value := ""
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil
+			}
+			unquoted, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil
+			}
+			value = unquoted
+			i = end + 1
+		} else {
+			start := i
+			for i < len(line) && line[i] != ' ' {
+				i++
+			}
+			value = line[start:i]
+		}
+
+		fields = append(fields, field{key: key, value: value})
+	}
+
+	// a single key=value is more likely to be plain text
+	if len(fields) < 2 {
+		return nil
+	}
+
+	return fields
+}
+
+func isKeyByte(b byte) bool {
+	return b == '_' || b == '.' || b == '-' || b == '@' ||
+		(b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
@@ -41,6 +41,12 @@ var (
 			Foreground(titleForeground)  // white
 	}()
 
+	// Tab of the active pane, in the top bar
+	activeTabStyle = lipgloss.NewStyle().
+			Background(titleForeground).
+			Foreground(titleBackground).
+			Bold(true)
+
 	helpLineStyle lipgloss.Style = lipgloss.NewStyle().
 			Background(softBackground).
 			Foreground(softForeground)
@@ -59,6 +65,72 @@ var (
 		Foreground(lipgloss.Color(BrightGray))
 		// white
 
+	// Footer badges with the state of the command
+	runningBadgeStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color(Blue)).
+				Foreground(lipgloss.Color(Black))
+
+	successBadgeStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color(Green)).
+				Foreground(lipgloss.Color(Black))
+
+	failureBadgeStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color(Red)).
+				Foreground(lipgloss.Color(BrightGray))
+
+	// Line separating the output of a run from what follows
+	successSeparatorStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color(Green))
+
+	failureSeparatorStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color(Red))
+
+	// Line between hunks of non-contiguous lines, when context is displayed
+	contextSeparatorStyle = lipgloss.NewStyle().
+				Foreground(softForeground)
+
+	// Line introducing a run, when the output of every run is kept
+	runHeaderStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(Purple)).
+			Bold(true)
+
+	// Line introducing a step of a pipeline
+	stepHeaderStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(Blue)).
+			Bold(true)
+
+	// Comparison of two runs
+	diffHunkStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(Cyan))
+
+	diffAddedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(Green)).
+			Bold(true)
+
+	diffRemovedStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color(Red)).
+				Bold(true)
+
+	// Structured log lines
+	fieldKeyStyle = lipgloss.NewStyle().
+			Foreground(softForeground)
+
+	debugLevelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(BrightBlack))
+
+	infoLevelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(Blue))
+
+	warnLevelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(Orange))
+
+	errorLevelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(Red))
+
+	fatalLevelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(BrightRed)).
+			Bold(true)
+
 	// Help View Styles
 	paragraphStyle = lipgloss.NewStyle().
 			Background(softBackground).
@@ -78,3 +150,43 @@ var (
 	separatorStyle = lipgloss.NewStyle().
 			Foreground(softForeground)
 )
+
+// Colors overriding the default ones, as ANSI color numbers or hex codes.
+// Empty values keep the default color
+type Theme struct {
+	Title           string // background of the top bar
+	TitleText       string // foreground of the top bar
+	Match           string // background of search matches
+	MatchText       string // foreground of search matches
+	ActiveMatch     string // background of the active search match
+	ActiveMatchText string // foreground of the active search match
+}
+
+// Applies the colors of theme to the styles of the user interface
+func ApplyTheme(theme Theme) {
+	if theme.Title != "" {
+		titleBackground = lipgloss.Color(theme.Title)
+	}
+	if theme.TitleText != "" {
+		titleForeground = lipgloss.Color(theme.TitleText)
+	}
+	titleStyle = titleStyle.
+		Background(titleBackground).
+		Foreground(titleForeground)
+	activeTabStyle = activeTabStyle.
+		Background(titleForeground).
+		Foreground(titleBackground)
+
+	if theme.Match != "" {
+		searchMatchStyle = searchMatchStyle.Background(lipgloss.Color(theme.Match))
+	}
+	if theme.MatchText != "" {
+		searchMatchStyle = searchMatchStyle.Foreground(lipgloss.Color(theme.MatchText))
+	}
+	if theme.ActiveMatch != "" {
+		activeMatchStyle = activeMatchStyle.Background(lipgloss.Color(theme.ActiveMatch))
+	}
+	if theme.ActiveMatchText != "" {
+		activeMatchStyle = activeMatchStyle.Foreground(lipgloss.Color(theme.ActiveMatchText))
+	}
+}
@@ -3,31 +3,93 @@ package viewport
 import (
 	"fmt"
 	"log"
-	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
-	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/muesli/reflow/wrap"
 
+	"github.com/gaelph/monique/filter"
 	"github.com/gaelph/monique/mediator"
 )
 
-// Set the whole content at once
+// Set the whole content of a pane at once
 type SetContentMsg struct {
+	Pane    string // name of the source, empty for every pane
 	Content string
 }
 
-// Append lines to the current content
+// Append lines to the current content of a pane
 type AppendContentMsg struct {
+	Pane    string // name of the source, empty for every pane
 	Content string
 }
 
-// Clear the whole content
-type ClearContentMsg struct{}
+// Clear the whole content of a pane
+type ClearContentMsg struct {
+	Pane string // name of the source, empty for every pane
+}
+
+// The command of a pane has started
+type StartMsg struct {
+	Pane    string
+	Command string
+	Changes mediator.ChangeSet // the files whose changes triggered the run, if any
+}
+
+// Replace the filter stack of a pane, the active one when Pane is empty,
+// with a single term, or clear it when Pattern is empty
+type SetFilterMsg struct {
+	Pane    string
+	Pattern string
+}
+
+// Search a pane, the active one when Pane is empty
+type SetSearchMsg struct {
+	Pane    string
+	Pattern string
+}
+
+// The output of the command of a pane was closed
+type OutputClosedMsg struct {
+	Pane string
+}
+
+// The command of a pane has exited
+type ExitMsg struct {
+	Pane   string
+	Status mediator.ExitStatus
+}
+
+// A step of the pipeline of a pane has started
+type StepMsg struct {
+	Pane string
+	Step mediator.Step
+}
+
+// The command of a pane will be restarted automatically
+type RestartScheduledMsg struct {
+	Pane    string
+	Restart mediator.ScheduledRestart
+}
+
+// The pending automatic restart of a pane was canceled
+type RestartCanceledMsg struct {
+	Pane string
+}
+
+// The file watcher has started, or its state changed
+type WatchStatusMsg struct {
+	Backend    string // like "fsnotify" or "polling 500ms"
+	Watches    int    // number of watched paths
+	Suppressed int    // events ignored as the content of the file did not change
+}
+
+// Refreshes the restart countdown
+type restartTickMsg struct{}
 
 // Type of text input
 type fieldStatus int8
@@ -50,36 +112,72 @@ const (
 
 // Model holding the state of the application
 type model struct {
-	mediator        mediator.Mediator // Communication Hub
-	command         string            // the command that was run
-	searchString    string            // the string to search for (displays matches)
-	filterString    string            // the string fo filter the results by (displays only matching lines)
-	keyMap          KeyMap            // key bindings
-	viewport        viewport.Model    // inner viewport component
-	searchResults   []searchMatch     // the search results
-	allLines        []string          // the whole content
-	filteredIndices []int             // indices of the lines that match the filter string
-	renderedLines   []string          // the rendered content (filtered with search decorations)
-	textinput       textinput.Model   // inner text input component
-	scrollPos       int               // current scroll position (although, it should match viewport.YOffset)
-	activeMatch     int               // the index of the active search match (in searchResults)
-	fieldStatus     fieldStatus       // current kind of input (filter or search)
-	ready           bool              // whether the model is ready to be rendered
-	showingHelp     bool
-}
-
-func NewModel(
-	command string,
-	mediator mediator.Mediator,
-) model {
+	*pane                             // the active pane
+	panes          []*pane            // one pane per source, plus a merged one when there are several
+	keyMap         KeyMap             // key bindings
+	viewport       rowViewport        // scrolls through the rendered lines
+	textinput      textinput.Model    // inner text input component
+	fieldStatus    fieldStatus        // current kind of input (filter or search)
+	ready          bool               // whether the model is ready to be rendered
+	pendingContent []AppendContentMsg // output received before the model was ready
+	showingHelp    bool
+	savedFilters   []string        // filter patterns recalled while filtering
+	savedFilter    int             // index of the recalled saved filter, -1 if none
+	watchStatus    *WatchStatusMsg // nil when no files are watched
+
+	showingFilters bool // whether the filter stack popup is shown
+	filterCursor   int  // index of the selected term in the filter stack popup
+	editingFilter  int  // index of the term being edited in the filter stack, -1 if none
+
+	contextBefore int // number of lines displayed before each matching line
+	contextAfter  int // number of lines displayed after each matching line
+
+	structured bool  // whether structured log lines are displayed as "time level msg key=value"
+	minLevel   level // lines of a lower level are hidden, noLevel to display every line
+
+	errorFile string // where the source locations are written after each run, if not empty
+	notice    string // outcome of the last action, shown until the next key press
+
+	keep        bool // whether the output of every run is kept, under a header
+	history     int  // number of runs kept by each pane, 0 for unlimited
+	showingRuns bool // whether the run picker is shown
+	runCursor   int  // index of the selected run in the run picker
+	markedRun   *run // the run the selected one is compared with, if any
+
+	replay       Replayer         // controls the replay of a recorded session, nil unless replaying
+	replayStatus *ReplayStatusMsg // how far the replay went, nil until it starts
+}
+
+func NewModel(options Options, sources []Source) model {
 	m := model{
-		command:     command,
-		mediator:    mediator,
-		activeMatch: -1,
-		viewport:    viewport.New(0, 0),
-		textinput:   textinput.New(),
-		keyMap:      DefaultKeyBinding(),
+		viewport:     newRowViewport(0, 0),
+		textinput:    textinput.New(),
+		keyMap:       options.KeyMap,
+		savedFilters: options.Filters,
+		savedFilter:  -1,
+
+		editingFilter: -1,
+		contextBefore: options.ContextBefore,
+		contextAfter:  options.ContextAfter,
+		structured:    options.Structured,
+		errorFile:     options.ErrorFile,
+		keep:          options.Keep,
+		history:       options.History,
+		replay:        options.Replay,
+	}
+
+	for _, source := range sources {
+		m.panes = append(m.panes, newPane(source, options.Scrollback))
+	}
+	if len(sources) > 1 {
+		m.panes = append(m.panes, newMergedPane(sources, options.Scrollback))
+	}
+	if options.Filter != "" {
+		for _, p := range m.panes {
+			p.filters = []filter.Term{filter.Parse(options.Filter)}
+		}
 	}
+	m.pane = m.panes[0]
 
 	// m.textinput.Focus()
 	m.textinput.Prompt = m.inputPrompt()
@@ -105,6 +203,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.textinput, cmd = m.textinput.Update(msg)
 	cmds = append(cmds, cmd)
 	// Update the search/filter strings with user input
+	filterString, searchString := m.filterString, m.searchString
 	m = m.updateStrings()
 
 	// TODO: check this behavior
@@ -113,6 +212,23 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		m.notice = ""
+
+		if m.showingFilters && !key.Matches(msg, m.keyMap.Quit) {
+			m = m.updateFilterStack(msg)
+			cmds = m.goToBottom(cmds)
+
+			return m, tea.Batch(cmds...)
+		}
+
+		if m.showingRuns && !key.Matches(msg, m.keyMap.Quit) {
+			var pickerCmds []tea.Cmd
+			m, pickerCmds = m.updateRunPicker(msg)
+			cmds = append(cmds, pickerCmds...)
+
+			return m, tea.Batch(cmds...)
+		}
+
 		switch {
 		case key.Matches(msg, m.keyMap.ShowHelp):
 			if !m.hasFocus() {
@@ -129,6 +245,26 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.restart()
 			return m, tea.Batch(cmds...)
 
+		// Switch between panes
+		case key.Matches(msg, m.keyMap.NextPane):
+			if !m.hasFocus() && len(m.panes) > 1 {
+				cmds = m.switchPane(1, cmds)
+				return m, tea.Batch(cmds...)
+			}
+
+		case key.Matches(msg, m.keyMap.PreviousPane):
+			if !m.hasFocus() && len(m.panes) > 1 {
+				cmds = m.switchPane(-1, cmds)
+				return m, tea.Batch(cmds...)
+			}
+
+		// Cancel the pending automatic restart
+		case key.Matches(msg, m.keyMap.CancelRestart):
+			if !m.hasFocus() && m.hasPendingRestart() {
+				m = m.cancelRestart()
+				return m, tea.Batch(cmds...)
+			}
+
 		// Reject the current search/filter
 		case key.Matches(msg, m.keyMap.Blur):
 			if m.showingHelp {
@@ -138,9 +274,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			m = m.blur()
-			m.filteredIndices = m.applyFilter(m.allLines)
-			m.renderedLines = m.renderContent(m.allLines, m.filteredIndices)
-			m.viewport.SetContent(strings.Join(m.renderedLines, "\n"))
+			m.refresh()
 			cmds = m.goToBottom(cmds)
 
 			return m, tea.Batch(cmds...)
@@ -160,6 +294,137 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, tea.Batch(cmds...)
 			}
 
+		// Show the filter stack
+		case key.Matches(msg, m.keyMap.FilterStack):
+			if !m.hasFocus() {
+				m.showingHelp = false
+				m = m.openFilterStack()
+
+				return m, tea.Batch(cmds...)
+			}
+
+		// Show the latest runs
+		case key.Matches(msg, m.keyMap.RunHistory):
+			if !m.hasFocus() {
+				m.showingHelp = false
+				m = m.openRunPicker()
+
+				return m, tea.Batch(cmds...)
+			}
+
+		// Scroll to the header of the next/previous run
+		case key.Matches(msg, m.keyMap.NextRunStart):
+			if !m.hasFocus() {
+				cmds = m.goToRunStart(1, cmds)
+				return m, tea.Batch(cmds...)
+			}
+
+		case key.Matches(msg, m.keyMap.PreviousRunStart):
+			if !m.hasFocus() {
+				cmds = m.goToRunStart(-1, cmds)
+				return m, tea.Batch(cmds...)
+			}
+
+		// Pause the replay, or move it forward/backward
+		case key.Matches(msg, m.keyMap.PauseReplay):
+			if !m.hasFocus() && m.replay != nil {
+				cmds = append(cmds, m.togglePause())
+				return m, tea.Batch(cmds...)
+			}
+
+		case key.Matches(msg, m.keyMap.SeekForward):
+			if !m.hasFocus() && m.replay != nil {
+				cmds = append(cmds, m.seek(seekStep))
+				return m, tea.Batch(cmds...)
+			}
+
+		case key.Matches(msg, m.keyMap.SeekBackward):
+			if !m.hasFocus() && m.replay != nil {
+				cmds = append(cmds, m.seek(-seekStep))
+				return m, tea.Batch(cmds...)
+			}
+
+		// Grow or shrink the context around matching lines
+		case key.Matches(msg, m.keyMap.MoreContext):
+			if !m.hasFocus() {
+				m = m.growContext(1)
+				return m, tea.Batch(cmds...)
+			}
+
+		case key.Matches(msg, m.keyMap.LessContext):
+			if !m.hasFocus() && m.hasContext() {
+				m = m.growContext(-1)
+				return m, tea.Batch(cmds...)
+			}
+
+		// Display structured log lines as they are, or by their fields
+		case key.Matches(msg, m.keyMap.ToggleStructured):
+			if !m.hasFocus() {
+				m.structured = !m.structured
+				m.refresh()
+				cmds = m.goToBottom(cmds)
+
+				return m, tea.Batch(cmds...)
+			}
+
+		// Hide the lines below a level
+		case key.Matches(msg, m.keyMap.ThresholdDebug):
+			if !m.hasFocus() {
+				m = m.setLevelThreshold(debugLevel)
+				return m, tea.Batch(cmds...)
+			}
+
+		case key.Matches(msg, m.keyMap.ThresholdInfo):
+			if !m.hasFocus() {
+				m = m.setLevelThreshold(infoLevel)
+				return m, tea.Batch(cmds...)
+			}
+
+		case key.Matches(msg, m.keyMap.ThresholdWarn):
+			if !m.hasFocus() {
+				m = m.setLevelThreshold(warnLevel)
+				return m, tea.Batch(cmds...)
+			}
+
+		case key.Matches(msg, m.keyMap.ThresholdError):
+			if !m.hasFocus() {
+				m = m.setLevelThreshold(errorLevel)
+				return m, tea.Batch(cmds...)
+			}
+
+		case key.Matches(msg, m.keyMap.ThresholdFatal):
+			if !m.hasFocus() {
+				m = m.setLevelThreshold(fatalLevel)
+				return m, tea.Batch(cmds...)
+			}
+
+		// Select the next/previous source location reported in the output
+		case key.Matches(msg, m.keyMap.NextError):
+			if !m.hasFocus() {
+				cmds = m.goToError(1, cmds)
+				return m, tea.Batch(cmds...)
+			}
+
+		case key.Matches(msg, m.keyMap.PreviousError):
+			if !m.hasFocus() {
+				cmds = m.goToError(-1, cmds)
+				return m, tea.Batch(cmds...)
+			}
+
+		// Write the source locations of the latest run to the error file
+		case key.Matches(msg, m.keyMap.ExportErrors):
+			if !m.hasFocus() {
+				cmds = append(cmds, m.exportErrors(m.errorFile))
+				return m, tea.Batch(cmds...)
+			}
+
+		// Open the selected source location in $EDITOR
+		case key.Matches(msg, m.keyMap.OpenError):
+			if !m.hasFocus() {
+				cmds = m.openError(cmds)
+				return m, tea.Batch(cmds...)
+			}
+
 		// Start search
 		case key.Matches(msg, m.keyMap.Search):
 			if !m.hasFocus() {
@@ -169,6 +434,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, tea.Batch(cmds...)
 			}
 
+		// Recall saved filters
+		case key.Matches(msg, m.keyMap.PreviousSaved):
+			if m.hasFocus() && m.fieldStatus == FILTER && len(m.savedFilters) > 0 {
+				m = m.recallSavedFilter(-1)
+			}
+
+		case key.Matches(msg, m.keyMap.NextSaved):
+			if m.hasFocus() && m.fieldStatus == FILTER && len(m.savedFilters) > 0 {
+				m = m.recallSavedFilter(1)
+			}
+
 		case key.Matches(msg, m.keyMap.HalfPageDown):
 			if !m.viewport.AtBottom() {
 				cmds = m.halfPageDown(cmds)
@@ -198,65 +474,162 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
-		m.filteredIndices = m.applyFilter(m.allLines)
-		m.searchResults, m.activeMatch = m.search(m.allLines, m.filteredIndices)
-		m.renderedLines = m.renderContent(m.allLines, m.filteredIndices)
-
 		// Sets the content with filter and search highlights if any
-		m.viewport.SetContent(strings.Join(m.renderedLines, "\n"))
+		if m.filterString != filterString || m.searchString != searchString {
+			m.refresh()
+		}
 		cmds = m.goToBottom(cmds)
 
 	// Sets the whole content at once
 	case SetContentMsg:
-		m.allLines = strings.Split(msg.Content, "\n")
-
-		m.filteredIndices = m.applyFilter(m.allLines)
-		m.searchResults, m.activeMatch = m.search(m.allLines, m.filteredIndices)
-		m.renderedLines = m.renderContent(m.allLines, m.filteredIndices)
-		m.viewport.SetContent(strings.Join(m.renderedLines, "\n"))
+		for _, p := range m.targets(msg.Pane) {
+			p.setContent(msg.Content)
+		}
 
-		if shouldBottom {
-			cmds = m.goToBottom(cmds)
+		if m.isTarget(msg.Pane) {
+			m.refresh()
+			if shouldBottom {
+				cmds = m.goToBottom(cmds)
+			}
 		}
 
 	// Appends to the current content
 	case AppendContentMsg:
-		log.Printf("🚀  ~ e/v/viewport.go:223 ~ msg.Content: %+v\n", msg.Content)
-		m.allLines = strings.Split(
-			strings.Join(m.allLines, "\n")+wrap.String(msg.Content, m.viewport.Width),
-			"\n",
-		)
+		// the lines are wrapped to the width of the terminal
+		if !m.ready {
+			m.pendingContent = append(m.pendingContent, msg)
+			break
+		}
+		cmds = m.appendContent(msg, shouldBottom, cmds)
+
+	case StartMsg:
+		if p := m.paneNamed(msg.Pane); p != nil {
+			// {changed} makes the command line differ from one run to the next
+			p.command = msg.Command
+			p.startRun(msg.Command, msg.Changes, m.history)
+			p.runStart = p.output().end()
+			p.previousRunning = p.running
+			p.inSteps = false
+			p.running = true
+			p.exitStatus = nil
+			p.pendingRestart = nil
+
+			if m.keep {
+				cmds = m.appendRunHeader(p, shouldBottom, cmds)
+			}
+		}
 
-		// lastLine := ""
-		// // pop the last line off
-		// if l := pop(&m.allLines); l != nil {
-		// 	lastLine = *l
-		// }
-		//
-		// lastLine += msg.Content
-		// newLines := strings.Split(lastLine, "\n")
-		//
-		// push(&m.allLines, newLines...)
-
-		m.filteredIndices = m.applyFilter(m.allLines)
-		m.searchResults, m.activeMatch = m.search(m.allLines, m.filteredIndices)
-		m.renderedLines = m.renderContent(m.allLines, m.filteredIndices)
-		m.viewport.SetContent(strings.Join(m.renderedLines, "\n"))
-
-		if shouldBottom {
-			cmds = m.goToBottom(cmds)
+	case ExitMsg:
+		if p := m.paneNamed(msg.Pane); p != nil {
+			p.running = false
+			p.exitStatus = &msg.Status
+			if run := p.exitedRun(); run != nil {
+				run.status = &msg.Status
+			}
+		}
+		if m.errorFile != "" {
+			cmds = append(cmds, m.exportErrors(m.errorFile))
+		}
+
+	case StepMsg:
+		if p := m.paneNamed(msg.Pane); p != nil {
+			// the previous run goes on until the pre-steps succeeded,
+			// the last step being the command itself
+			if msg.Step.Index < msg.Step.Total && !p.inSteps {
+				p.inSteps = true
+				p.running = p.previousRunning
+			} else if msg.Step.Index == msg.Step.Total {
+				p.inSteps = false
+				p.running = true
+				p.exitStatus = nil
+			}
+		}
+
+	case SetFilterMsg:
+		if p := m.paneOrActive(msg.Pane); p != nil {
+			p.filters = nil
+			if msg.Pattern != "" {
+				p.filters = []filter.Term{filter.Parse(msg.Pattern)}
+			}
+			if p == m.pane {
+				m.refresh()
+			}
+		}
+
+	case SetSearchMsg:
+		if p := m.paneOrActive(msg.Pane); p != nil {
+			p.searchString = msg.Pattern
+			p.activeMatch = -1
+			if p == m.pane {
+				// the search field would bring the previous search back
+				if m.fieldStatus == SEARCH {
+					m.textinput.SetValue(msg.Pattern)
+				}
+				m.refresh()
+			}
+		}
+
+	case OutputClosedMsg:
+		// the standard input is not read again
+		if p := m.paneNamed(msg.Pane); p != nil && p.input {
+			p.closed = true
+		}
+
+	case RestartScheduledMsg:
+		if p := m.paneNamed(msg.Pane); p != nil {
+			p.pendingRestart = &msg.Restart
+			cmds = append(cmds, restartTick())
+		}
+
+	case RestartCanceledMsg:
+		if p := m.paneNamed(msg.Pane); p != nil {
+			p.pendingRestart = nil
+		}
+
+	case WatchStatusMsg:
+		m.watchStatus = &msg
+
+	case ReplayStatusMsg:
+		m.replayStatus = &msg
+
+	case ResetMsg:
+		m = m.reset()
+
+	case errorsExportedMsg:
+		log.Println(msg)
+		m.notice = msg.String()
+
+	case editorExitMsg:
+		if msg.err != nil {
+			log.Printf("Editor: %s\n", msg.err)
+		}
+
+	case restartTickMsg:
+		if m.hasPendingRestart() {
+			cmds = append(cmds, restartTick())
 		}
 
 	// Clears the whole content
 	case ClearContentMsg:
-		m.allLines = []string{}
-		m.renderedLines = []string{}
-		m.viewport.SetContent("")
-		cmds = m.goToTop(cmds)
+		for _, p := range m.targets(msg.Pane) {
+			// the merged pane keeps the output of every run
+			if !p.isMerged() || msg.Pane == "" {
+				p.clear()
+			}
+		}
+
+		if m.pane.name == msg.Pane || msg.Pane == "" {
+			m.viewport.SetContent("")
+			cmds = m.goToTop(cmds)
+		}
 
 	// Resize the viewport
 	case tea.WindowSizeMsg:
 		m, cmds = m.resize(msg, cmds)
+		for _, pending := range m.pendingContent {
+			cmds = m.appendContent(pending, true, cmds)
+		}
+		m.pendingContent = nil
 	}
 
 	// Handle keyboard and mouse events in the viewport
@@ -274,41 +647,49 @@ func (m model) View() string {
 	content := ""
 	if m.showingHelp {
 		content = m.helpView()
+	} else if m.showingFilters {
+		content = m.filterStackView()
+	} else if m.showingRuns {
+		content = m.runPickerView()
 	} else {
-		m.viewport.SetContent(strings.Join(m.renderedLines, "\n"))
 		content = m.viewport.View()
 	}
 	return fmt.Sprintf("%s\n%s\n%s", m.headerView(), content, m.footerView())
 }
 
-// Returns all the line indices
-func (m model) everything(lines []string) []int {
-	indices := make([]int, len(m.allLines))
-	for i := range lines {
-		indices[i] = i
-	}
-	return indices
+// Apply the filter and return the matching line numbers, without context
+func (m model) applyFilter(lines *scrollback) (indices []int) {
+	return m.filterLines(lines, lines.first(), lines.end(), []int{})
 }
 
-// Apply the filter and return the matching indices
-func (m model) applyFilter(lines []string) (indices []int) {
-	if m.filterString == "" {
-		return m.everything(lines)
-	}
+// Appends the numbers of the lines between from and to that pass
+// the filter terms to indices
+func (m model) filterLines(lines *scrollback, from, to int, indices []int) []int {
+	filters := compileFilters(m.activeFilters())
 
-	pattern := addTopLevelCapture(m.filterString)
-	if !shouldCaseSensitive(pattern) {
-		pattern = makeInsensitive(pattern)
+	// the output line the wrapped lines are part of
+	var head styledLine
+	if from < to {
+		head = lines.at(lines.head(from))
 	}
 
-	reg, err := regexp.Compile(pattern)
-	if err != nil {
-		return m.everything(lines)
-	}
+	for i := from; i < to; i++ {
+		line := lines.at(i)
+		if !line.continued {
+			head = line
+		}
+		// the output of each run is introduced by its header
+		if head.runHeader {
+			indices = append(indices, i)
+			continue
+		}
 
-	indices = make([]int, 0)
-	for i, line := range lines {
-		if reg.Match([]byte(line)) {
+		rec := head.record
+		if m.isHidden(line, rec) || m.isBelowThreshold(head.level) {
+			continue
+		}
+
+		if matchesFilters(filters, m.displayLine(line).text, rec) {
 			indices = append(indices, i)
 		}
 	}
@@ -323,9 +704,42 @@ func (m model) quit() (model, tea.Cmd) {
 }
 
 func (m model) restart() {
-	if m.mediator != nil {
-		m.mediator.SendRequestRestart()
+	for _, p := range m.sources() {
+		p.mediator.SendRequestRestart()
+	}
+}
+
+func (m model) cancelRestart() model {
+	for _, p := range m.sources() {
+		if p.pendingRestart != nil {
+			p.pendingRestart = nil
+			p.mediator.SendCancelRestart()
+		}
+	}
+
+	return m
+}
+
+// Activates the pane at offset from the active one, looping around
+func (m *model) switchPane(offset int, cmds []tea.Cmd) []tea.Cmd {
+	current := 0
+	for i, p := range m.panes {
+		if p == m.pane {
+			current = i
+		}
+	}
+
+	// the term being typed is kept on the stack of the pane it filters
+	if m.hasFocus() {
+		*m = m.accept()
 	}
+	m.pane.scrollPos = m.viewport.YOffset
+	m.pane = m.panes[(current+offset+len(m.panes))%len(m.panes)]
+
+	m.refresh()
+	m.viewport.SetYOffset(m.scrollPos)
+
+	return cmds
 }
 
 func (m model) blur() model {
@@ -336,16 +750,40 @@ func (m model) blur() model {
 }
 
 func (m model) accept() model {
+	if m.fieldStatus == FILTER {
+		m = m.pushFilter()
+	}
 	m.textinput.Blur()
 
 	return m
 }
 
+// Reminds of the prefixes of the filter terms while the input is empty
+var filterPlaceholder = fmt.Sprintf("regex, %sregex to exclude, %sfield expression like level>=warn",
+	filter.ExcludePrefix, filter.ExprPrefix)
+
 func (m model) startFilter() model {
 	m.fieldStatus = FILTER
+	m.savedFilter = -1
+	m.editingFilter = -1
 	m.textinput.Focus()
 	m.textinput.SetValue(m.filterString)
 	m.textinput.Prompt = m.inputPrompt()
+	m.textinput.Placeholder = filterPlaceholder
+
+	return m
+}
+
+// Replaces the filter with the saved filter at offset from the current one
+func (m model) recallSavedFilter(offset int) model {
+	if m.savedFilter < 0 && offset < 0 {
+		m.savedFilter = len(m.savedFilters)
+	}
+	m.savedFilter = clampLoop(m.savedFilter+offset, 0, len(m.savedFilters)-1)
+
+	m.textinput.SetValue(m.savedFilters[m.savedFilter])
+	m.textinput.CursorEnd()
+	m.filterString = m.textinput.Value()
 
 	return m
 }
@@ -355,28 +793,52 @@ func (m model) startSearch() model {
 	m.textinput.Focus()
 	m.textinput.SetValue(m.searchString)
 	m.textinput.Prompt = m.inputPrompt()
+	m.textinput.Placeholder = ""
 
 	return m
 }
 
 func (m *model) goToNextMatch(cmds []tea.Cmd) []tea.Cmd {
+	previous := m.activeMatch
 	m.activeMatch = m.getNextActiveMatch()
 	nextLine := m.getActiveMatchLine()
 
-	m.renderedLines = m.renderContent(m.allLines, m.filteredIndices)
-	m.viewport.SetContent(strings.Join(m.renderedLines, "\n"))
+	m.renderMatchLines(previous, m.activeMatch)
 	return m.goToLine(nextLine, cmds)
 }
 
 func (m *model) goToPreviousMatch(cmds []tea.Cmd) []tea.Cmd {
+	previous := m.activeMatch
 	m.activeMatch = m.getPreviousActiveMatch()
 	nextLine := m.getActiveMatchLine()
 
-	m.renderedLines = m.renderContent(m.allLines, m.filteredIndices)
-	m.viewport.SetContent(strings.Join(m.renderedLines, "\n"))
+	m.renderMatchLines(previous, m.activeMatch)
 	return m.goToLine(nextLine, cmds)
 }
 
+// Renders again the lines of the given search matches, as one of them
+// became active
+func (m *model) renderMatchLines(matches ...int) {
+	lineNrs := make([]int, 0, len(matches))
+	for _, match := range matches {
+		if match >= 0 && match < len(m.searchResults) {
+			lineNrs = append(lineNrs, m.searchResults[match].line)
+		}
+	}
+	m.renderLines(lineNrs...)
+}
+
+// Renders again the displayed lines among those numbered lineNrs
+func (m *model) renderLines(lineNrs ...int) {
+	for _, lineNr := range lineNrs {
+		i := sort.SearchInts(m.filteredIndices, lineNr)
+		if i < len(m.filteredIndices) && m.filteredIndices[i] == lineNr {
+			m.renderedLines[i] = m.renderContent(m.allLines, m.filteredIndices[i:i+1])[0]
+		}
+	}
+	m.setViewportContent()
+}
+
 // MARK - Viewport Navigation
 
 func (m *model) goToMatch(match int, cmds []tea.Cmd) []tea.Cmd {
@@ -442,28 +904,207 @@ func (m model) resize(
 		// we can initialize the viewport. The initial dimensions come in
 		// quickly, though asynchronously, which is why we wait for them
 		// here.
-		m.viewport = viewport.New(msg.Width, msg.Height-verticalMarginHeight)
-		m.viewport.YPosition = headerHeight + 1
-		m.viewport.HighPerformanceRendering = false
-
+		m.viewport = newRowViewport(msg.Width, msg.Height-verticalMarginHeight)
 		m.ready = true
-
-		// This is only necessary for high performance rendering, which in
-		// most cases you won't need.
-		//
-		// Render the viewport one line below the header.
-		m.viewport.YPosition = headerHeight + 1
 	} else {
 		m.viewport.Width = msg.Width
-		m.viewport.YPosition = headerHeight + 1
 		m.viewport.Height = msg.Height - verticalMarginHeight
 	}
 
 	return m, cmds
 }
 
+// Appends output to the panes displaying it
+func (m *model) appendContent(msg AppendContentMsg, shouldBottom bool, cmds []tea.Cmd) []tea.Cmd {
+	from := 0
+	for _, p := range m.targets(msg.Pane) {
+		n := 0
+		if p.isMerged() && msg.Pane != "" {
+			n = p.appendFrom(msg.Pane, msg.Content, m.contentWidth())
+		} else {
+			n = p.appendContent(msg.Content, m.contentWidth())
+		}
+		if p == m.pane {
+			from = n
+		}
+	}
+
+	return m.showAppended(msg.Pane, from, shouldBottom, cmds)
+}
+
 // MARK: - Utilities
 
+// Applies the filter and search of the active pane, and renders its content
+func (m *model) refresh() {
+	m.matchedLines = m.applyFilter(m.allLines)
+	m.filteredIndices = m.withContext(m.matchedLines, m.allLines.first(), m.allLines.end())
+	m.searchResults, m.activeMatch = m.search(m.allLines, m.filteredIndices)
+	m.renderedLines = m.renderContent(m.allLines, m.filteredIndices)
+	m.setViewportContent()
+}
+
+// Applies the filter and search of the active pane to its lines from the
+// line numbered from, the previous ones being unchanged apart from those
+// dropped from the scrollback, and renders them.
+// Returns the number of rows of the viewport dropped from the top
+func (m *model) refreshFrom(from int) int {
+	first := m.allLines.first()
+	// new matching lines may display lines before from as their context
+	from = max(from-m.contextBefore, first)
+
+	dropped := sort.SearchInts(m.filteredIndices, first)
+	kept := sort.SearchInts(m.filteredIndices, from)
+	droppedRows := m.rowOf(dropped)
+	m.filteredIndices = m.filteredIndices[dropped:kept]
+	m.renderedLines = m.renderedLines[dropped:kept]
+
+	droppedMatches := sort.SearchInts(m.matchedLines, first)
+	keptMatches := sort.SearchInts(m.matchedLines, from)
+	m.matchedLines = m.matchedLines[droppedMatches:keptMatches]
+
+	activeID := m.activeMatchID()
+	droppedResults := m.searchResultsFrom(first)
+	keptResults := m.searchResultsFrom(from)
+	m.searchResults = m.searchResults[droppedResults:keptResults]
+	if m.activeMatch >= 0 {
+		m.activeMatch = max(m.activeMatch-droppedResults, 0)
+	}
+
+	matches := m.filterLines(m.allLines, from, m.allLines.end(), []int{})
+	// the last matching line before from may have context lines after it
+	withPrevious := matches
+	if len(m.matchedLines) > 0 {
+		withPrevious = append([]int{m.matchedLines[len(m.matchedLines)-1]}, matches...)
+	}
+	m.matchedLines = append(m.matchedLines, matches...)
+
+	indices := m.withContext(withPrevious, from, m.allLines.end())
+	m.filteredIndices = append(m.filteredIndices, indices...)
+	m.searchResults = m.searchLines(m.allLines, indices, m.searchResults)
+	if (m.activeMatch == -1 && len(m.searchResults) > 0) ||
+		m.activeMatch > len(m.searchResults)-1 {
+		m.activeMatch = len(m.searchResults) - 1
+	}
+
+	m.renderedLines = append(m.renderedLines, m.renderContent(m.allLines, indices)...)
+	if activeID >= 0 && m.activeMatchID() != activeID {
+		// the active match was dropped, or was on a line that changed:
+		// the line of the new one is highlighted
+		m.renderActiveLine()
+	}
+	m.setViewportContent()
+
+	return droppedRows
+}
+
+// Renders again the line of the active search match
+func (m *model) renderActiveLine() {
+	if m.activeMatch < 0 || m.activeMatch >= len(m.searchResults) {
+		return
+	}
+
+	lineNr := m.searchResults[m.activeMatch].line
+	i := sort.SearchInts(m.filteredIndices, lineNr)
+	if i < len(m.filteredIndices) && m.filteredIndices[i] == lineNr {
+		m.renderedLines[i] = m.renderContent(m.allLines, []int{lineNr})[0]
+	}
+}
+
+// Filters, searches and renders the lines appended to the panes of the
+// named source from the line numbered from, if the active pane is one of them
+func (m *model) showAppended(name string, from int, shouldBottom bool, cmds []tea.Cmd) []tea.Cmd {
+	// an older run, or a comparison, stays displayed
+	if !m.isTarget(name) || m.isBrowsing() {
+		return cmds
+	}
+
+	// only the new lines are filtered and searched
+	dropped := m.refreshFrom(from)
+	if shouldBottom {
+		cmds = m.goToBottom(cmds)
+	} else if dropped > 0 {
+		// keeps the same lines in view
+		m.viewport.SetYOffset(m.viewport.YOffset - dropped)
+		m.scrollPos = m.viewport.YOffset
+	}
+
+	return cmds
+}
+
+// The panes a message for the named source applies to:
+// every pane when name is empty, otherwise the pane of the source
+// and the merged pane
+func (m model) targets(name string) []*pane {
+	if name == "" {
+		return m.panes
+	}
+
+	targets := make([]*pane, 0, 2)
+	for _, p := range m.panes {
+		if p.name == name || p.isMerged() {
+			targets = append(targets, p)
+		}
+	}
+
+	return targets
+}
+
+// Whether a message for the named source changes the active pane
+func (m model) isTarget(name string) bool {
+	return name == "" || m.pane.name == name || m.pane.isMerged()
+}
+
+func (m model) paneNamed(name string) *pane {
+	for _, p := range m.panes {
+		if !p.isMerged() && p.name == name {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// The pane of the named source, or the active pane when name is empty
+func (m model) paneOrActive(name string) *pane {
+	if name == "" {
+		return m.pane
+	}
+
+	return m.paneNamed(name)
+}
+
+// The panes whose command is controlled by the active pane:
+// every source for the merged pane, or the active pane itself
+func (m model) sources() []*pane {
+	if !m.pane.isMerged() {
+		return []*pane{m.pane}
+	}
+
+	sources := make([]*pane, 0, len(m.panes)-1)
+	for _, p := range m.panes {
+		if !p.isMerged() {
+			sources = append(sources, p)
+		}
+	}
+
+	return sources
+}
+
+func (m model) hasPendingRestart() bool {
+	for _, p := range m.sources() {
+		if p.pendingRestart != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Width of the lines of output, next to the gutter
+func (m model) contentWidth() int {
+	return max(m.viewport.Width-gutterWidth, 1)
+}
+
 func (m model) hasFocus() bool {
 	return m.textinput.Focused()
 }
@@ -490,6 +1131,7 @@ func (m model) clearCurrentString() model {
 	switch m.fieldStatus {
 	case FILTER:
 		m.filterString = ""
+		m.editingFilter = -1
 		m.textinput.SetValue(m.filterString)
 	case SEARCH:
 		m.searchString = ""
@@ -515,8 +1157,22 @@ func (m model) updateStrings() model {
 }
 
 func (m model) headerView() string {
-	title := fmt.Sprintf(" Monique: %s", m.command)
-	helpText := "help [?] "
+	title := fmt.Sprintf(" Monique: %s", m.description())
+	if len(m.panes) > 1 {
+		title = " Monique:" + m.tabsView()
+	}
+	helpText := fmt.Sprintf("help %s ", keysOf(m.keyMap.ShowHelp))
+	for _, p := range m.sources() {
+		if p.pendingRestart != nil {
+			helpText = fmt.Sprintf(
+				"%s | cancel %s | %s",
+				p.restartCountdown(),
+				keysOf(m.keyMap.CancelRestart),
+				helpText,
+			)
+			break
+		}
+	}
 	space := strings.Repeat(
 		" ",
 		max(0, m.viewport.Width-lipgloss.Width(title)-lipgloss.Width(helpText)),
@@ -525,10 +1181,51 @@ func (m model) headerView() string {
 	return titleStyle.Render(fmt.Sprintf("%s%s%s", title, space, helpText))
 }
 
+// One tab per pane, the active one highlighted
+func (m model) tabsView() string {
+	builder := strings.Builder{}
+	for _, p := range m.panes {
+		tab := fmt.Sprintf(" %s ", p.title())
+		if p == m.pane {
+			tab = activeTabStyle.Render(tab)
+		} else {
+			tab = titleStyle.Render(tab)
+		}
+		builder.WriteString(titleStyle.Render(" ") + tab)
+	}
+
+	return builder.String()
+}
+
 func (m model) footerView() string {
 	statusLine := ""
-	if m.filterString != "" {
-		statusLine = fmt.Sprintf("Filter: %s | ", m.filterString)
+	for _, p := range m.sources() {
+		statusLine += p.exitBadge(len(m.panes) > 1)
+	}
+	if m.watchStatus != nil {
+		statusLine += fmt.Sprintf("%s · %d watches", m.watchStatus.Backend, m.watchStatus.Watches)
+		if m.watchStatus.Suppressed > 0 {
+			statusLine += fmt.Sprintf(" · %d unchanged", m.watchStatus.Suppressed)
+		}
+		statusLine += " | "
+	}
+	if replay := m.replayStatusView(); replay != "" {
+		statusLine += replay + " | "
+	}
+	if browsing := m.browsingStatus(); browsing != "" {
+		statusLine += browsing + " | "
+	}
+	if m.structured {
+		statusLine += "Structured | "
+	}
+	if m.minLevel != noLevel {
+		statusLine += fmt.Sprintf("Level: %s+ | ", m.minLevel)
+	}
+	if filters := m.filtersStatus(); filters != "" {
+		statusLine += fmt.Sprintf("Filter: %s | ", filters)
+		if m.hasContext() {
+			statusLine += m.contextStatus() + " | "
+		}
 	}
 	if m.searchString != "" {
 		statusLine += fmt.Sprintf("Search: %s |", m.searchString)
@@ -536,12 +1233,49 @@ func (m model) footerView() string {
 
 	help := ""
 	if m.hasFocus() {
-		help += "[esc] to cancel | [enter] to accept"
+		if m.fieldStatus == FILTER && len(m.savedFilters) > 0 {
+			help += fmt.Sprintf("%s saved filters | ", keysOf(m.keyMap.PreviousSaved, m.keyMap.NextSaved))
+		}
+		help += fmt.Sprintf(
+			"%s to cancel | %s to accept",
+			keysOf(m.keyMap.Blur),
+			keysOf(m.keyMap.Accept),
+		)
 	} else {
 		if m.hasSearchResults() {
-			help += "[n/N] next/previous match | "
+			help += fmt.Sprintf(
+				"%s next/previous match | ",
+				keysOf(m.keyMap.NextMatch, m.keyMap.PreviousMatch),
+			)
+		}
+		if m.locationAt(m.activeError) != nil {
+			help += fmt.Sprintf(
+				"%s next/previous error | %s open | ",
+				keysOf(m.keyMap.NextError, m.keyMap.PreviousError),
+				keysOf(m.keyMap.OpenError),
+			)
+		}
+		help += fmt.Sprintf(
+			"%s to search | %s to filter",
+			keysOf(m.keyMap.Search),
+			keysOf(m.keyMap.Filter),
+		)
+		if len(m.filters) > 0 {
+			help += fmt.Sprintf(" | %s filters", keysOf(m.keyMap.FilterStack))
+		}
+		if len(m.runs) > 1 {
+			help += fmt.Sprintf(" | %s runs", keysOf(m.keyMap.RunHistory))
+		}
+		if m.keep {
+			help += fmt.Sprintf(" | %s previous/next run", keysOf(m.keyMap.PreviousRunStart, m.keyMap.NextRunStart))
+		}
+		if m.replay != nil {
+			help += fmt.Sprintf(
+				" | %s pause | %s seek",
+				keysOf(m.keyMap.PauseReplay),
+				keysOf(m.keyMap.SeekBackward, m.keyMap.SeekForward),
+			)
 		}
-		help += "[/] to search | [f] to filter"
 	}
 
 	space := strings.Repeat(
@@ -558,28 +1292,105 @@ func (m model) footerView() string {
 	input := ""
 	if m.hasFocus() {
 		input = m.textinput.View()
-	} else if m.hasSearchResults() {
-		count := len(m.searchResults)
-		n := count - (m.activeMatch)
-		input = fmt.Sprintf("[%d of %d]", n, count)
+	} else {
+		if m.hasSearchResults() {
+			count := len(m.searchResults)
+			n := count - (m.activeMatch)
+			input = fmt.Sprintf("[%d of %d] ", n, count)
+		}
+		input += m.errorView()
+		if m.notice != "" {
+			input = m.notice
+		}
 	}
 
 	return fmt.Sprintf("%s\n%s", helpLine, input)
 }
 
-func (m model) renderContent(lines []string, indices []int) []string {
+// Like "restarting in 4s (attempt 3/10)"
+func (p *pane) restartCountdown() string {
+	remaining := time.Until(p.pendingRestart.At).Round(time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	attempt := fmt.Sprintf("%d", p.pendingRestart.Attempt)
+	if p.pendingRestart.MaxAttempts > 0 {
+		attempt = fmt.Sprintf("%d/%d", p.pendingRestart.Attempt, p.pendingRestart.MaxAttempts)
+	}
+
+	if p.name != "" {
+		return fmt.Sprintf("%s restarting in %s (attempt %s)", p.name, remaining, attempt)
+	}
+	return fmt.Sprintf("restarting in %s (attempt %s)", remaining, attempt)
+}
+
+func restartTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return restartTickMsg{}
+	})
+}
+
+// Badge showing whether the command is running, or how it exited,
+// prefixed with the name of the source if named is true
+func (p *pane) exitBadge(named bool) string {
+	prefix := ""
+	if named {
+		prefix = p.name + ": "
+	}
+
+	if p.running {
+		return runningBadgeStyle.Render(" "+prefix+"running ") + " "
+	}
+	if p.exitStatus == nil {
+		return ""
+	}
+
+	badge := fmt.Sprintf(" %s%s · %s ", prefix, p.exitStatus.Short(), formatDuration(p.exitStatus.Duration))
+	if p.exitStatus.Success() {
+		return successBadgeStyle.Render(badge) + " "
+	}
+	return failureBadgeStyle.Render(badge) + " "
+}
+
+func (m model) renderContent(lines *scrollback, indices []int) []string {
 	content := make([]string, len(indices))
 	totalLines := m.viewport.TotalLineCount()
+	activeID := m.activeMatchID()
+
+	// search results are sorted by line, like indices
+	next := 0
+	if len(indices) > 0 {
+		next = m.searchResultsFrom(indices[0])
+	}
 
 	for i, lineNr := range indices {
-		matches := m.searchResultsAtLine(lineNr)
-		content[i] = decorateLine(
-			lines[lineNr],
-			matches,
-			m.activeMatch,
+		for next < len(m.searchResults) && m.searchResults[next].line < lineNr {
+			next++
+		}
+		end := next
+		for end < len(m.searchResults) && m.searchResults[end].line == lineNr {
+			end++
+		}
+
+		output := lines.at(lineNr)
+		line := m.displayLine(output)
+		if m.isContextLine(lineNr) {
+			line = dimmed(line)
+		}
+		// locations are found in the output as it is, not in structured records
+		if loc := m.locationAt(lineNr); loc != nil && !(m.structured && output.record != nil) {
+			line = highlightLocation(line, loc, lineNr == m.activeError)
+		}
+
+		content[i] = gutter(lines.at(lines.head(lineNr)).level) + decorateLine(
+			line,
+			m.searchResults[next:end],
+			activeID,
 			lineNr,
 			totalLines,
 		)
+		next = end
 	}
 
 	return content
@@ -0,0 +1,59 @@
+package watcher
+
+import (
+	"github.com/fsnotify/fsnotify"
+)
+
+// A source of file system events, for the directories added to it.
+// Like fsnotify, directories are not watched recursively
+type backend interface {
+	Add(path string) error
+	Remove(path string) error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	Close() error
+	Name() string // like "fsnotify" or "polling 500ms"
+	Count() int   // number of watched paths
+}
+
+// Backend relying on the events of the operating system
+type notifyBackend struct {
+	notifier *fsnotify.Watcher
+}
+
+func newNotifyBackend() (*notifyBackend, error) {
+	notifier, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	return &notifyBackend{notifier: notifier}, nil
+}
+
+func (b *notifyBackend) Add(path string) error {
+	return b.notifier.Add(path)
+}
+
+func (b *notifyBackend) Remove(path string) error {
+	return b.notifier.Remove(path)
+}
+
+func (b *notifyBackend) Events() <-chan fsnotify.Event {
+	return b.notifier.Events
+}
+
+func (b *notifyBackend) Errors() <-chan error {
+	return b.notifier.Errors
+}
+
+func (b *notifyBackend) Close() error {
+	return b.notifier.Close()
+}
+
+func (b *notifyBackend) Name() string {
+	return "fsnotify"
+}
+
+func (b *notifyBackend) Count() int {
+	return len(b.notifier.WatchList())
+}
@@ -0,0 +1,86 @@
+package watcher
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Size and hash of the content of a file
+type contentHash struct {
+	size int64
+	sum  uint64
+}
+
+// Content of the watched files, as last seen, to tell actual changes
+// from writes of identical content and touched modification times
+type hashCache struct {
+	hashes map[string]contentHash // absolute path -> content
+	mu     sync.Mutex
+}
+
+func newHashCache() *hashCache {
+	return &hashCache{hashes: make(map[string]contentHash)}
+}
+
+func hashFile(path string) (contentHash, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return contentHash{}, err
+	}
+	defer file.Close()
+
+	digest := xxhash.New()
+	size, err := io.Copy(digest, file)
+	if err != nil {
+		return contentHash{}, err
+	}
+
+	return contentHash{size: size, sum: digest.Sum64()}, nil
+}
+
+// Remembers the content of files, so that a first write of identical
+// content is not taken for a change
+func (c *hashCache) prime(paths []string) {
+	for _, path := range paths {
+		hash, err := hashFile(path)
+		if err != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		if _, known := c.hashes[path]; !known {
+			c.hashes[path] = hash
+		}
+		c.mu.Unlock()
+	}
+}
+
+// Whether the content of path differs from when it was last seen,
+// remembering its new content.
+// Files that cannot be read are considered changed
+func (c *hashCache) changed(path string) bool {
+	hash, err := hashFile(path)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		delete(c.hashes, path)
+		return true
+	}
+
+	previous, known := c.hashes[path]
+	c.hashes[path] = hash
+
+	return !known || previous != hash
+}
+
+func (c *hashCache) forget(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.hashes, path)
+}
@@ -0,0 +1,147 @@
+package watcher
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Files listing patterns of paths to ignore, in the directory they apply to
+var ignoreFileNames = []string{".gitignore", ".ignore"}
+
+// Directories that are never watched
+var defaultIgnores = []string{".git", ".hg", ".svn"}
+
+// A pattern of paths to ignore, with .gitignore semantics
+type ignoreRule struct {
+	base    string // absolute path of the directory the pattern is relative to
+	pattern string // doublestar pattern, relative to base
+	negate  bool   // a "!pattern", re-including what a previous rule ignored
+	dirOnly bool   // a "pattern/", only matching directories
+}
+
+// Rules from -ignore flags and ignore files, the last matching rule winning
+type ignoreList struct {
+	rules  []ignoreRule
+	loaded map[string]bool // directories whose ignore files were loaded
+}
+
+// Parses a line of an ignore file, or a -ignore pattern, relative to base.
+// ok is false for blank lines and comments
+func parseIgnoreRule(base string, line string) (rule ignoreRule, ok bool, err error) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false, nil
+	}
+
+	rule.base = base
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		// escaped leading "!" or "#"
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// a pattern with a slash (other than a trailing one) is relative to base,
+	// otherwise it matches at any depth
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		line = "**/" + line
+	}
+
+	if !doublestar.ValidatePattern(line) {
+		return rule, false, fmt.Errorf("invalid pattern %q", line)
+	}
+
+	rule.pattern = line
+	return rule, true, nil
+}
+
+// Adds a rule, relative to base
+func (l *ignoreList) add(base string, pattern string) error {
+	rule, ok, err := parseIgnoreRule(base, pattern)
+	if err != nil {
+		return err
+	}
+	if ok {
+		l.rules = append(l.rules, rule)
+	}
+
+	return nil
+}
+
+// Loads the ignore files found in dir, if any
+func (l *ignoreList) load(dir string) {
+	// directories are walked again when the watcher falls back to polling
+	if l.loaded[dir] {
+		return
+	}
+	if l.loaded == nil {
+		l.loaded = make(map[string]bool)
+	}
+	l.loaded[dir] = true
+
+	for _, name := range ignoreFileNames {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if err := l.add(dir, scanner.Text()); err != nil {
+				log.Printf("ERROR: %s: %s\n", filepath.Join(dir, name), err)
+			}
+		}
+		file.Close()
+	}
+}
+
+// Whether path (absolute) is ignored, either itself or one of its parent
+// directories, as nothing can be re-included from an ignored directory
+func (l *ignoreList) ignored(path string, isDir bool) bool {
+	if len(l.rules) == 0 {
+		return false
+	}
+
+	parent := filepath.Dir(path)
+	if parent != path && l.ignored(parent, true) {
+		return true
+	}
+
+	return l.matches(path, isDir)
+}
+
+// Whether the last rule matching path itself ignores it
+func (l *ignoreList) matches(path string, isDir bool) bool {
+	ignored := false
+
+	for _, rule := range l.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		rel, err := filepath.Rel(rule.base, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		if matched, _ := doublestar.Match(rule.pattern, filepath.ToSlash(rel)); matched {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
@@ -0,0 +1,126 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	const root = "/project"
+
+	tests := []struct {
+		name  string
+		rules []string
+		path  string // relative to root
+		isDir bool
+		want  bool
+	}{
+		// unanchored patterns match at any depth
+		{"unanchored file", []string{"*.log"}, "app.log", false, true},
+		{"unanchored nested file", []string{"*.log"}, "logs/today/app.log", false, true},
+		{"unanchored other file", []string{"*.log"}, "app.go", false, false},
+		{"unanchored name", []string{"node_modules"}, "web/node_modules", true, true},
+		{"inside unanchored name", []string{"node_modules"}, "web/node_modules/react/index.js", false, true},
+
+		// anchored patterns only match relative to their directory
+		{"anchored", []string{"/build"}, "build", true, true},
+		{"anchored elsewhere", []string{"/build"}, "cmd/build", true, false},
+		{"anchored by a slash", []string{"docs/*.md"}, "docs/intro.md", false, true},
+		{"anchored by a slash elsewhere", []string{"docs/*.md"}, "api/docs/intro.md", false, false},
+		{"anchored doublestar", []string{"gen/**/*.pb.go"}, "gen/api/v1/user.pb.go", false, true},
+
+		// "!" re-includes what a previous rule ignored, the last rule winning
+		{"re-included", []string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{"not re-included", []string{"*.log", "!keep.log"}, "drop.log", false, true},
+		{"ignored again", []string{"*.log", "!keep.log", "keep.log"}, "keep.log", false, true},
+		{"re-included from an ignored directory", []string{"vendor/", "!vendor/keep.go"}, "vendor/keep.go", false, true},
+		{"escaped !", []string{`\!important`}, "!important", false, true},
+
+		// a trailing "/" only matches directories
+		{"directory rule on a directory", []string{"tmp/"}, "tmp", true, true},
+		{"directory rule on a file", []string{"tmp/"}, "tmp", false, false},
+		{"inside a directory rule", []string{"tmp/"}, "tmp/cache.db", false, true},
+		{"nested directory rule", []string{"tmp/"}, "web/tmp", true, true},
+
+		{"comment", []string{"# *.go"}, "main.go", false, false},
+		{"no rules", nil, "main.go", false, false},
+	}
+
+	for _, test := range tests {
+		l := &ignoreList{}
+		for _, rule := range test.rules {
+			if err := l.add(root, rule); err != nil {
+				t.Fatalf("%s: add(%q) failed: %v", test.name, rule, err)
+			}
+		}
+
+		path := filepath.Join(root, test.path)
+		if got := l.ignored(path, test.isDir); got != test.want {
+			t.Errorf("%s: ignored(%q) = %v with %q, want %v", test.name, test.path, got, test.rules, test.want)
+		}
+	}
+}
+
+func TestIgnoredOutsideTheBase(t *testing.T) {
+	l := &ignoreList{}
+	if err := l.add("/project/web", "*.js"); err != nil {
+		t.Fatal(err)
+	}
+
+	if l.ignored("/project/api/index.js", false) {
+		t.Errorf("a rule of web/ ignores a file of api/")
+	}
+	if !l.ignored("/project/web/src/index.js", false) {
+		t.Errorf("a rule of web/ does not ignore a file of web/src/")
+	}
+}
+
+func TestLoadIgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "web")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(path string, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(root, ".gitignore"), "# generated\n*.gen.go\n/dist/\n")
+	write(filepath.Join(sub, ".ignore"), "/dist/\n!keep.gen.go\n")
+
+	l := &ignoreList{}
+	l.load(root)
+	l.load(sub)
+	l.load(sub) // directories are walked again when polling
+
+	if len(l.rules) != 4 {
+		t.Fatalf("%d rules loaded, want 4", len(l.rules))
+	}
+
+	tests := []struct {
+		path  string // relative to root
+		isDir bool
+		want  bool
+	}{
+		{"api.gen.go", false, true},
+		{"web/ui.gen.go", false, true},
+		{"web/keep.gen.go", false, false},
+		{"keep.gen.go", false, true},
+		{"dist", true, true},
+		{"web/dist", true, true},
+		{"web/src/dist", true, false},
+	}
+	for _, test := range tests {
+		if got := l.ignored(filepath.Join(root, test.path), test.isDir); got != test.want {
+			t.Errorf("ignored(%q) = %v, want %v", test.path, got, test.want)
+		}
+	}
+}
+
+func TestParseIgnoreRuleRejectsInvalidPatterns(t *testing.T) {
+	if _, _, err := parseIgnoreRule("/project", "[unclosed"); err == nil {
+		t.Errorf("parseIgnoreRule(%q) succeeded, want an error", "[unclosed")
+	}
+}
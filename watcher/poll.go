@@ -0,0 +1,193 @@
+package watcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Interval of the polling backend, when it is used because the operating
+// system could not watch the directories
+const DefaultPollInterval = 500 * time.Millisecond
+
+// What is compared between two polls
+type fileState struct {
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// Backend listing the watched directories at regular intervals, for file
+// systems where events are not available, like NFS or some container mounts
+type pollBackend struct {
+	interval    time.Duration
+	directories map[string]map[string]fileState // directory -> name -> state
+	events      chan fsnotify.Event
+	errors      chan error
+	done        chan struct{}
+	closeOnce   sync.Once
+	mu          sync.Mutex
+}
+
+func newPollBackend(interval time.Duration) *pollBackend {
+	b := &pollBackend{
+		interval:    interval,
+		directories: make(map[string]map[string]fileState),
+		events:      make(chan fsnotify.Event),
+		errors:      make(chan error),
+		done:        make(chan struct{}),
+	}
+	go b.poll()
+
+	return b
+}
+
+func (b *pollBackend) Add(path string) error {
+	entries, err := list(path)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.directories[path] = entries
+
+	return nil
+}
+
+func (b *pollBackend) Remove(path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.directories, path)
+
+	return nil
+}
+
+func (b *pollBackend) Events() <-chan fsnotify.Event {
+	return b.events
+}
+
+func (b *pollBackend) Errors() <-chan error {
+	return b.errors
+}
+
+func (b *pollBackend) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+
+	return nil
+}
+
+func (b *pollBackend) Name() string {
+	return fmt.Sprintf("polling %s", b.interval)
+}
+
+func (b *pollBackend) Count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.directories)
+}
+
+func (b *pollBackend) poll() {
+	defer close(b.events)
+	defer close(b.errors)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+		}
+
+		// events are sent without holding the lock, as they may lead
+		// to directories being added or removed
+		for _, event := range b.changes() {
+			select {
+			case b.events <- event:
+			case <-b.done:
+				return
+			}
+		}
+	}
+}
+
+// Lists the watched directories again, and returns what changed since
+// the previous poll
+func (b *pollBackend) changes() []fsnotify.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var events []fsnotify.Event
+	for directory, previous := range b.directories {
+		current, err := list(directory)
+		if err != nil {
+			// the directory is gone, along with its content
+			for name := range previous {
+				events = append(events, fsnotify.Event{Name: filepath.Join(directory, name), Op: fsnotify.Remove})
+			}
+			events = append(events, fsnotify.Event{Name: directory, Op: fsnotify.Remove})
+			delete(b.directories, directory)
+			continue
+		}
+
+		for name, state := range current {
+			path := filepath.Join(directory, name)
+			before, existed := previous[name]
+			switch {
+			case !existed:
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+				if !state.isDir {
+					events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+				}
+			case state.isDir != before.isDir:
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Remove})
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+			case !state.isDir && (state.size != before.size || !state.modTime.Equal(before.modTime)):
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+			}
+		}
+		for name := range previous {
+			if _, exists := current[name]; !exists {
+				events = append(events, fsnotify.Event{Name: filepath.Join(directory, name), Op: fsnotify.Remove})
+			}
+		}
+
+		b.directories[directory] = current
+	}
+
+	return events
+}
+
+// The state of each entry of a directory
+func list(directory string) (map[string]fileState, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]fileState, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// removed since the directory was read
+			continue
+		}
+
+		states[entry.Name()] = fileState{
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			isDir:   info.IsDir(),
+		}
+	}
+
+	return states, nil
+}
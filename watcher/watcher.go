@@ -1,47 +1,127 @@
 package watcher
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/fsnotify/fsnotify"
 )
 
+// State of the watcher, as shown to the user
+type Status struct {
+	Backend    string // like "fsnotify" or "polling 500ms"
+	Watches    int    // number of watched paths
+	Suppressed int    // events ignored as the content of the file did not change
+}
+
+// How long events are gathered into a single change set, after the first one
+const batchWindow = 100 * time.Millisecond
+
+// A file that changed
+type Change struct {
+	Path string // as named by the events, relative to the working directory if the watched path is
+	Kind string // "write" or "rename"
+}
+
+// A watched directory
+type watchRoot struct {
+	abs  string // absolute path
+	path string // as given, events being named after it
+}
+
 type Watcher struct {
-	notifier       *fsnotify.Watcher
-	changeListener func(string, string)
+	backend        backend
+	pollInterval   time.Duration // 0 to rely on file system events
+	changeListener func([]Change)
+	pending        []Change // changes of the current batch, in order
+	statusListener func(Status)
+	lastStatus     Status     // as last sent to the status listener
+	hashes         *hashCache // nil when changes are not checked against the content of files
+	unhashed       []string   // files found while walking, whose content is not known yet
+	suppressed     int
 	directories    []string
+	roots          []watchRoot
+	parents        []string // directories of the watched files
 	patterns       []*regexp.Regexp
+	includes       []string    // doublestar patterns a file must match, if any
+	ignorePatterns []string    // -ignore patterns, added to the rules of each root
+	ignores        *ignoreList // paths not to watch
+	useIgnoreFiles bool        // whether to honor .gitignore and .ignore files
 }
 
 func NewWatcher(directories []string, extensions []string) *Watcher {
-	notifier, _ := fsnotify.NewWatcher()
-	patterns := make([]*regexp.Regexp, len(extensions))
+	patterns := make([]*regexp.Regexp, 0, len(extensions))
 
-	for i, ext := range extensions {
-		r := regexp.MustCompile(fmt.Sprintf(`\%s$`, ext))
-		patterns[i] = r
+	for _, ext := range extensions {
+		if ext == "" {
+			continue
+		}
+		r := regexp.MustCompile(fmt.Sprintf(`%s$`, regexp.QuoteMeta(ext)))
+		patterns = append(patterns, r)
 	}
 
 	return &Watcher{
-		notifier:    notifier,
-		directories: directories,
-		patterns:    patterns,
+		directories:    directories,
+		patterns:       patterns,
+		ignores:        &ignoreList{},
+		hashes:         newHashCache(),
+		useIgnoreFiles: true,
 	}
 }
 
 func (w *Watcher) SetChangeListener(
-	changeListener func(string, string),
+	changeListener func([]Change),
 ) *Watcher {
 	w.changeListener = changeListener
 
 	return w
 }
 
+// Only report changes to files matching one of the patterns.
+// Patterns without a slash match file names, others match paths relative
+// to the watched directories, like "src/**/*.go"
+func (w *Watcher) SetIncludes(patterns []string) error {
+	for _, pattern := range patterns {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid include pattern %q", pattern)
+		}
+	}
+
+	w.includes = patterns
+	return nil
+}
+
+// Neither watch nor report changes to paths matching one of the patterns,
+// which follow the syntax of .gitignore files, relative to the watched directories
+func (w *Watcher) SetIgnores(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, _, err := parseIgnoreRule("", pattern); err != nil {
+			return fmt.Errorf("invalid ignore pattern: %w", err)
+		}
+	}
+
+	w.ignorePatterns = patterns
+	return nil
+}
+
+// Whether to honor .gitignore and .ignore files found in the watched directories
+func (w *Watcher) SetUseIgnoreFiles(use bool) *Watcher {
+	w.useIgnoreFiles = use
+
+	return w
+}
+
 func (w *Watcher) Start() {
+	w.backend = w.newBackend()
+
 	for _, directory := range w.directories {
 		file, err := os.Lstat(directory)
 		if err != nil {
@@ -50,12 +130,12 @@ func (w *Watcher) Start() {
 		}
 
 		if file.IsDir() {
-			// starting at the root of the project, walk each file/directory searching for
-			// directories
-			if err := filepath.Walk(directory, watchDir(w)); err != nil {
+			root, err := filepath.Abs(directory)
+			if err != nil {
 				log.Println("ERROR", err)
 				continue
 			}
+			w.addRoot(root, directory)
 		} else if file.Mode()&os.ModeSymlink == os.ModeSymlink {
 			log.Printf("ERROR: %s is a symlink\n", directory)
 		} else {
@@ -63,79 +143,316 @@ func (w *Watcher) Start() {
 			directory, _ := filepath.EvalSymlinks(directory)
 			parent := filepath.Dir(directory)
 			basename := filepath.Base(directory)
-			w.notifier.Add(parent)
 			r, err := regexp.Compile(fmt.Sprintf("%s$", regexp.QuoteMeta(basename)))
 			if err != nil {
 				log.Printf("ERROR: %s is not a valid filename", directory)
 				continue
 			}
+			w.parents = append(w.parents, parent)
 			w.patterns = append(w.patterns, r)
+			if abs, err := filepath.Abs(directory); err == nil {
+				w.unhashed = append(w.unhashed, abs)
+			}
 		}
 	}
 
+	w.watchAll()
+	w.hashUnhashed()
+
 	go func() {
+		w.notifyStatus()
+
+		// fires at the end of the batch window, nil outside of a batch
+		var flush <-chan time.Time
+
 		for {
 			select {
 			// watch for events
-			case event := <-w.notifier.Events:
-				if event.Op.Has(fsnotify.Remove) ||
-					event.Op.Has(fsnotify.Rename) {
-					w.notifier.Remove(event.Name)
-				}
-
-				if event.Op.Has(fsnotify.Write) ||
-					event.Op.Has(fsnotify.Rename) {
-					if len(w.patterns) == 0 {
-						log.Printf(
-							"Change detected[%s]: %s\n",
-							event.Op.String(),
-							event.Name,
-						)
-						w.changeListener(event.Name, event.Op.String())
-					} else {
-						for _, pattern := range w.patterns {
-							if pattern.MatchString(event.Name) {
-								log.Printf("Change detected[%s]: %s\n", event.Op.String(), event.Name)
-								w.changeListener(event.Name, event.Op.String())
-								break
-							}
-						}
-					}
+			case event, ok := <-w.backend.Events():
+				if !ok {
+					return
 				}
+				w.handleEvent(event)
+				w.notifyStatus()
 
-				fileInfo, err := os.Stat(event.Name)
-				if err != nil {
-					continue
+				if len(w.pending) > 0 && flush == nil {
+					flush = time.After(batchWindow)
 				}
 
-				if fileInfo.IsDir() {
-					if event.Op.Has(fsnotify.Create) {
-						w.notifier.Add(event.Name)
-					}
-					if event.Op.Has(fsnotify.Remove) {
-						w.notifier.Remove(event.Name)
-					}
-				}
+			case <-flush:
+				flush = nil
+				changes := w.pending
+				w.pending = nil
+				w.changeListener(changes)
 
 				// watch for errors
-			case err := <-w.notifier.Errors:
+			case err, ok := <-w.backend.Errors():
+				if !ok {
+					return
+				}
 				log.Println("ERROR", err)
 			}
 		}
 	}()
 }
 
+// Polls at the given interval instead of relying on file system events,
+// that are not available on some file systems
+func (w *Watcher) SetPollInterval(interval time.Duration) *Watcher {
+	w.pollInterval = interval
+
+	return w
+}
+
+// Whether changes are only reported when the content of the file differs,
+// not when it is rewritten identically or merely touched
+func (w *Watcher) SetCompareContent(compare bool) *Watcher {
+	if compare {
+		w.hashes = newHashCache()
+	} else {
+		w.hashes = nil
+	}
+
+	return w
+}
+
+// Called with the status of the watcher when it starts, and when it changes
+func (w *Watcher) SetStatusListener(
+	statusListener func(Status),
+) *Watcher {
+	w.statusListener = statusListener
+
+	return w
+}
+
+func (w *Watcher) newBackend() backend {
+	if w.pollInterval > 0 {
+		return newPollBackend(w.pollInterval)
+	}
+
+	notifier, err := newNotifyBackend()
+	if err != nil {
+		log.Printf("ERROR: could not watch files: %s, polling every %s instead\n", err, DefaultPollInterval)
+		return newPollBackend(DefaultPollInterval)
+	}
+
+	return notifier
+}
+
+// Adds the watched directories, and their subdirectories, to the backend
+func (w *Watcher) watchAll() {
+	for _, parent := range w.parents {
+		if err := w.backend.Add(parent); err != nil {
+			w.handleWatchError(err)
+			return
+		}
+	}
+
+	for _, root := range w.roots {
+		// starting at the root of the project, walk each file/directory searching for
+		// directories
+		if err := filepath.Walk(root.path, watchDir(w)); err != nil {
+			w.handleWatchError(err)
+			return
+		}
+	}
+}
+
+// Falls back to polling when the operating system cannot watch more
+// directories, like when inotify watches run out
+func (w *Watcher) handleWatchError(err error) {
+	if _, polling := w.backend.(*pollBackend); polling || !errors.Is(err, syscall.ENOSPC) {
+		log.Println("ERROR", err)
+		return
+	}
+
+	log.Printf("ERROR: could not watch more directories: %s, polling every %s instead\n", err, DefaultPollInterval)
+	w.backend.Close()
+	w.backend = newPollBackend(DefaultPollInterval)
+	w.watchAll()
+}
+
+func (w *Watcher) notifyStatus() {
+	if w.statusListener == nil {
+		return
+	}
+
+	status := Status{
+		Backend:    w.backend.Name(),
+		Watches:    w.backend.Count(),
+		Suppressed: w.suppressed,
+	}
+	if status == w.lastStatus {
+		return
+	}
+	w.lastStatus = status
+	w.statusListener(status)
+}
+
+func (w *Watcher) handleEvent(event fsnotify.Event) {
+	path, err := filepath.Abs(event.Name)
+	if err != nil {
+		return
+	}
+
+	if event.Op.Has(fsnotify.Remove) ||
+		event.Op.Has(fsnotify.Rename) {
+		w.backend.Remove(event.Name)
+		if w.hashes != nil {
+			w.hashes.forget(path)
+		}
+	}
+
+	fileInfo, statErr := os.Stat(event.Name)
+	isDir := statErr == nil && fileInfo.IsDir()
+
+	if w.ignores.ignored(path, isDir) {
+		return
+	}
+
+	if event.Op.Has(fsnotify.Write) ||
+		event.Op.Has(fsnotify.Rename) {
+		if !isDir && w.matches(path) {
+			if w.hashes != nil && statErr == nil && !w.hashes.changed(path) {
+				log.Printf("Unchanged content[%s]: %s\n", event.Op.String(), event.Name)
+				w.suppressed++
+				return
+			}
+
+			log.Printf("Change detected[%s]: %s\n", event.Op.String(), event.Name)
+			w.addChange(event)
+		}
+	}
+
+	if isDir && event.Op.Has(fsnotify.Create) {
+		// watch the new directory, and whatever it already contains
+		if err := filepath.Walk(event.Name, watchDir(w)); err != nil {
+			w.handleWatchError(err)
+		}
+		w.hashUnhashed()
+	}
+}
+
+// Adds the change of a file to the current batch, once
+func (w *Watcher) addChange(event fsnotify.Event) {
+	change := Change{Path: filepath.Clean(event.Name), Kind: "write"}
+	if event.Op.Has(fsnotify.Rename) {
+		change.Kind = "rename"
+	}
+
+	for _, pending := range w.pending {
+		if pending == change {
+			return
+		}
+	}
+	w.pending = append(w.pending, change)
+}
+
+// Hashes the files found while walking, in the background
+func (w *Watcher) hashUnhashed() {
+	if w.hashes != nil && len(w.unhashed) > 0 {
+		go w.hashes.prime(w.unhashed)
+	}
+	w.unhashed = nil
+}
+
+// Whether a file (absolute path) matches the extensions and include patterns
+func (w *Watcher) matches(path string) bool {
+	if len(w.patterns) > 0 {
+		matched := false
+		for _, pattern := range w.patterns {
+			if pattern.MatchString(path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return w.included(path)
+}
+
+// Whether a file (absolute path) matches one of the include patterns, if any
+func (w *Watcher) included(path string) bool {
+	if len(w.includes) == 0 {
+		return true
+	}
+
+	name := filepath.Base(path)
+	for _, pattern := range w.includes {
+		if !strings.Contains(pattern, "/") {
+			if matched, _ := doublestar.Match(pattern, name); matched {
+				return true
+			}
+			continue
+		}
+
+		for _, root := range w.roots {
+			rel, err := filepath.Rel(root.abs, path)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			if matched, _ := doublestar.Match(pattern, filepath.ToSlash(rel)); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Registers a watched directory, with the ignore rules relative to it
+func (w *Watcher) addRoot(root string, path string) {
+	w.roots = append(w.roots, watchRoot{abs: root, path: path})
+
+	for _, pattern := range defaultIgnores {
+		w.ignores.add(root, pattern+"/")
+	}
+	for _, pattern := range w.ignorePatterns {
+		w.ignores.add(root, pattern)
+	}
+}
+
 func (w *Watcher) Close() {
-	w.notifier.Close()
+	if w.backend != nil {
+		w.backend.Close()
+	}
 }
 
 // watchDir gets run as a walk func, searching for directories to add watchers to
 func watchDir(w *Watcher) func(string, os.FileInfo, error) error {
 	return func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// the directory may have been removed in the meantime
+			return nil
+		}
+
 		// since fsnotify can watch all the files in a directory, watchers only need
 		// to be added to each nested directory
 		if fi.Mode().IsDir() {
-			return w.notifier.Add(path)
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+
+			if w.ignores.ignored(abs, true) {
+				return filepath.SkipDir
+			}
+
+			if w.useIgnoreFiles {
+				w.ignores.load(abs)
+			}
+
+			return w.backend.Add(path)
+		}
+
+		if w.hashes != nil && fi.Mode().IsRegular() {
+			abs, err := filepath.Abs(path)
+			if err == nil && w.matches(abs) && !w.ignores.ignored(abs, false) {
+				w.unhashed = append(w.unhashed, abs)
+			}
 		}
 
 		return nil
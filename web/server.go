@@ -0,0 +1,424 @@
+// Package web serves the output of the commands to browsers, live, each
+// with its own filter and search, applied by the server
+package web
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaelph/monique/filter"
+	"github.com/gaelph/monique/mediator"
+)
+
+//go:embed index.html
+var index []byte
+
+// Number of lines kept for the browsers connecting later
+const backlogSize = 10000
+
+// Number of messages waiting to be sent to a browser, beyond which it is
+// disconnected, to catch up once it reconnects
+const clientBuffer = 4096
+
+// A command whose output is served
+type Source struct {
+	Name     string
+	Command  string
+	Mediator mediator.Mediator
+}
+
+// Serves the web interface, and the events of the commands
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+	sources  []*source
+	mu       sync.Mutex
+	backlog  []line // the latest lines of every command
+	lastID   int    // the id of the last line
+	clients  map[*client]bool
+	host     string // the host of the listen address, empty when it names none
+	port     string // the port listened on
+}
+
+// Names of the host the server is always reached by, on its port
+var loopbackHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// Listens on addr, like "127.0.0.1:7777"
+func NewServer(addr string, sources ...Source) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener: listener,
+		clients:  make(map[*client]bool),
+	}
+	// ":7777" or "0.0.0.0:7777" don't name the host of the server
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsUnspecified() {
+			s.host = host
+		}
+	}
+	_, s.port, _ = net.SplitHostPort(listener.Addr().String())
+	for _, src := range sources {
+		state := &source{
+			server:  s,
+			name:    src.Name,
+			command: src.Command,
+		}
+		src.Mediator.AddListener(state)
+		s.sources = append(s.sources, state)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.HandleFunc("/restart", func(w http.ResponseWriter, r *http.Request) {
+		s.handleRestart(w, r, sources)
+	})
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	return s, nil
+}
+
+// Address the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Serves the browsers, until the server is closed
+func (s *Server) Serve() {
+	if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Println("http:", err)
+	}
+}
+
+// Stops listening, and disconnects the browsers
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(index)
+}
+
+// Restarts the command named by the source parameter, or every command
+func (s *Server) handleRestart(w http.ResponseWriter, r *http.Request, sources []Source) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.sameOrigin(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	name := r.URL.Query().Get("source")
+	restarted := false
+	for _, src := range sources {
+		if name == "" || src.Name == name {
+			src.Mediator.SendRequestRestart()
+			restarted = true
+		}
+	}
+	if !restarted {
+		http.Error(w, fmt.Sprintf("unknown command %q", name), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Whether the request comes from the page served, or from outside a
+// browser. Other sites can't read the events nor restart the commands,
+// even once their name resolves to the address of the server
+func (s *Server) sameOrigin(r *http.Request) bool {
+	if !s.servesHost(r.Host) {
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// browsers leave it out of the requests of the page to its own
+		// server, but tell where the others come from
+		site := r.Header.Get("Sec-Fetch-Site")
+		return site == "" || site == "same-origin" || site == "none"
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && u.Scheme == "http" && s.servesHost(u.Host)
+}
+
+// Whether host, like "localhost:7777", is the listen address of the
+// server, or a loopback name on its port
+func (s *Server) servesHost(host string) bool {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = host, "80"
+	}
+	if port != s.port {
+		return false
+	}
+
+	name = strings.ToLower(name)
+	if s.host != "" && name == strings.ToLower(s.host) {
+		return true
+	}
+
+	return slices.Contains(loopbackHosts, name)
+}
+
+// Streams the events of the commands, as server-sent events: the lines kept,
+// then the new ones, as they come in
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if !s.sameOrigin(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	c, invalid := newClient(query.Get("source"), query.Get("filter"), query.Get("search"))
+
+	// a browser reconnecting only needs the lines it did not receive
+	lastID, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	messages := []string{}
+	for _, err := range invalid {
+		messages = append(messages, message("invalid", 0, map[string]string{"error": err.Error()}))
+	}
+
+	s.mu.Lock()
+	statuses := make([]status, len(s.sources))
+	for i, src := range s.sources {
+		statuses[i] = src.status()
+	}
+	messages = append(messages, message("sources", 0, statuses))
+	for _, l := range s.backlog {
+		if l.id > lastID && c.accepts(l) {
+			messages = append(messages, c.render(l))
+		}
+	}
+	s.clients[c] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.mu.Unlock()
+	}()
+
+	for _, msg := range messages {
+		if _, err := w.Write([]byte(msg)); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case msg := <-c.messages:
+			if _, err := w.Write([]byte(msg)); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-c.dropped:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Keeps a line, and sends it to the browsers it passes the filter of
+func (s *Server) addLine(name string, kind string, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	l := line{id: s.lastID, source: name, kind: kind, text: text}
+	s.backlog = append(s.backlog, l)
+	if len(s.backlog) > backlogSize {
+		s.backlog = s.backlog[len(s.backlog)-backlogSize:]
+	}
+
+	for c := range s.clients {
+		if c.accepts(l) {
+			c.send(c.render(l))
+		}
+	}
+}
+
+// Sends how a command is doing to the browsers
+func (s *Server) sendStatus(st status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := message("status", 0, st)
+	for c := range s.clients {
+		c.send(msg)
+	}
+}
+
+// A server-sent event, with an id when it is not 0
+func message(event string, id int, data any) string {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Println("http:", err)
+		encoded = []byte("null")
+	}
+
+	msg := fmt.Sprintf("event: %s\n", event)
+	if id > 0 {
+		msg += fmt.Sprintf("id: %d\n", id)
+	}
+
+	return msg + fmt.Sprintf("data: %s\n\n", encoded)
+}
+
+// MARK: - Lines
+
+// A line of output, or a line telling how a run started or ended
+type line struct {
+	id     int    // increasing with each line, from 1
+	source string // the name of the command
+	kind   string // "output", or the event the line tells about
+	text   string // the visible text, without escape sequences
+}
+
+// Kinds of lines
+const (
+	kindOutput  = "output"
+	kindStart   = "start"
+	kindStep    = "step"
+	kindExit    = "exit"
+	kindKill    = "kill"
+	kindError   = "error"
+	kindRestart = "restart"
+)
+
+// A line as sent to the browser. Parts alternate between the text around
+// the search matches, and the matches themselves
+type renderedLine struct {
+	Source string   `json:"source"`
+	Kind   string   `json:"kind"`
+	Parts  []string `json:"parts"`
+}
+
+// MARK: - Clients
+
+// A browser receiving the events, with its own filter and search
+type client struct {
+	source   string         // the name of the command it follows, empty for every command
+	filter   *regexp.Regexp // nil when every line is sent
+	exclude  bool           // whether the lines matching the filter are left out
+	search   *regexp.Regexp // nil when not searching
+	messages chan string
+	dropped  chan struct{} // closed once the browser could not keep up
+	drop     sync.Once
+}
+
+// A client with the filter and search given, leaving out the invalid
+// ones, which filter nothing out like in the user interface
+func newClient(source string, filterInput string, search string) (*client, []error) {
+	c := &client{
+		source:   source,
+		messages: make(chan string, clientBuffer),
+		dropped:  make(chan struct{}),
+	}
+
+	invalid := []error{}
+	term := filter.Parse(filterInput)
+	if term.Pattern != "" {
+		reg, err := term.Regexp()
+		if err != nil {
+			invalid = append(invalid, fmt.Errorf("filter: %w", err))
+		} else {
+			c.filter, c.exclude = reg, term.Exclude
+		}
+	}
+	if search != "" {
+		reg, err := filter.CompilePattern(search)
+		if err != nil {
+			invalid = append(invalid, fmt.Errorf("search: %w", err))
+		} else {
+			c.search = reg
+		}
+	}
+
+	return c, invalid
+}
+
+// Whether the line is sent to the browser: the lines telling how runs
+// start and end always are, like run headers in the user interface
+func (c *client) accepts(l line) bool {
+	if c.source != "" && l.source != c.source {
+		return false
+	}
+	if l.kind != kindOutput || c.filter == nil {
+		return true
+	}
+
+	return c.filter.MatchString(l.text) != c.exclude
+}
+
+func (c *client) render(l line) string {
+	parts := []string{l.text}
+	if c.search != nil && l.kind == kindOutput {
+		parts = highlight(l.text, c.search)
+	}
+
+	return message("line", l.id, renderedLine{Source: l.source, Kind: l.kind, Parts: parts})
+}
+
+// Queues a message, disconnecting the browser when it can't keep up
+func (c *client) send(msg string) {
+	select {
+	case c.messages <- msg:
+	default:
+		c.drop.Do(func() { close(c.dropped) })
+	}
+}
+
+// The text around the matches of search, and the matches, alternating
+func highlight(text string, search *regexp.Regexp) []string {
+	parts := []string{}
+	last := 0
+	for _, match := range search.FindAllStringIndex(text, -1) {
+		if match[0] == match[1] {
+			continue
+		}
+		parts = append(parts, text[last:match[0]], text[match[0]:match[1]])
+		last = match[1]
+	}
+
+	return append(parts, text[last:])
+}
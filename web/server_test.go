@@ -0,0 +1,97 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gaelph/monique/mediator"
+)
+
+func newTestServer(t *testing.T, addr string) *Server {
+	s, err := NewServer(addr, Source{Name: "api", Command: "api", Mediator: mediator.NewMediator()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestSameOrigin(t *testing.T) {
+	s := newTestServer(t, "127.0.0.1:0")
+	port := s.port
+
+	tests := []struct {
+		name    string
+		host    string
+		headers map[string]string
+		want    bool
+	}{
+		{"curl", "127.0.0.1:" + port, nil, true},
+		{"localhost", "localhost:" + port, nil, true},
+		{"ipv6 loopback", "[::1]:" + port, nil, true},
+		{"page", "localhost:" + port, map[string]string{"Origin": "http://localhost:" + port}, true},
+		{"page by another loopback name", "127.0.0.1:" + port, map[string]string{"Origin": "http://localhost:" + port}, true},
+		{"same-origin fetch", "127.0.0.1:" + port, map[string]string{"Sec-Fetch-Site": "same-origin"}, true},
+		{"rebound name", "evil.example:" + port, nil, false},
+		{"rebound page", "evil.example:" + port, map[string]string{"Origin": "http://evil.example:" + port}, false},
+		{"other port", "localhost:1", nil, false},
+		{"other site", "localhost:" + port, map[string]string{"Origin": "http://evil.example"}, false},
+		{"other site on the port", "localhost:" + port, map[string]string{"Origin": "http://evil.example:" + port}, false},
+		{"opaque origin", "localhost:" + port, map[string]string{"Origin": "null"}, false},
+		{"cross-site without origin", "localhost:" + port, map[string]string{"Sec-Fetch-Site": "cross-site"}, false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/restart", nil)
+		r.Host = test.host
+		for key, value := range test.headers {
+			r.Header.Set(key, value)
+		}
+
+		if got := s.sameOrigin(r); got != test.want {
+			t.Errorf("%s: sameOrigin() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSameOriginAcceptsTheListenAddress(t *testing.T) {
+	s := newTestServer(t, "localhost:0")
+	s.host = "monique.lan"
+
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Host = "Monique.lan:" + s.port
+	r.Header.Set("Origin", "http://monique.lan:"+s.port)
+	if !s.sameOrigin(r) {
+		t.Errorf("requests to %s are rejected", r.Host)
+	}
+}
+
+func TestForbiddenRequests(t *testing.T) {
+	s := newTestServer(t, "127.0.0.1:0")
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		path := "/events"
+		if method == http.MethodPost {
+			path = "/restart"
+		}
+
+		r := httptest.NewRequest(method, path, nil)
+		r.Host = "evil.example:" + s.port
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s to %s answered %d, want %d", method, path, r.Host, w.Code, http.StatusForbidden)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/restart", nil)
+	r.Host = "127.0.0.1:" + s.port
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("POST /restart to %s answered %d, want %d", r.Host, w.Code, http.StatusNoContent)
+	}
+}
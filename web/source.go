@@ -0,0 +1,146 @@
+package web
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaelph/monique/mediator"
+	"github.com/gaelph/monique/plain"
+)
+
+// How a command is doing, as sent to the browser
+type status struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	Running bool   `json:"running"`
+	Exit    string `json:"exit,omitempty"` // how the last run ended, like "exit 1", if it did
+	Success bool   `json:"success"`        // whether the last run exited with a 0 code
+}
+
+// Turns what happens to a command into lines, and keeps how it is doing.
+// Implements mediator.MediatorListener
+type source struct {
+	server  *Server
+	name    string
+	mu      sync.Mutex
+	command string
+	running bool
+	exit    *mediator.ExitStatus
+	partial string // incomplete last line of output
+}
+
+func (src *source) status() status {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	st := status{Name: src.name, Command: src.command, Running: src.running}
+	if src.exit != nil {
+		st.Exit = src.exit.Short()
+		st.Success = src.exit.Success()
+	}
+
+	return st
+}
+
+// Sends the incomplete last line of output, if any
+func (src *source) flush() {
+	src.mu.Lock()
+	partial := src.partial
+	src.partial = ""
+	src.mu.Unlock()
+
+	if partial != "" {
+		src.server.addLine(src.name, kindOutput, plain.StripEscapes(partial))
+	}
+}
+
+// MARK: - MediatorListener
+
+func (src *source) OnStart(command string) {
+	src.flush()
+
+	src.mu.Lock()
+	src.command = command
+	src.running = true
+	src.exit = nil
+	src.mu.Unlock()
+
+	src.server.addLine(src.name, kindStart, fmt.Sprintf("Starting %s", command))
+	src.server.sendStatus(src.status())
+}
+
+func (src *source) OnError(err error) {
+	src.mu.Lock()
+	src.running = false
+	src.mu.Unlock()
+
+	src.server.addLine(src.name, kindError, fmt.Sprintf("Error: %s", err))
+	src.server.sendStatus(src.status())
+}
+
+func (src *source) OnKill() {
+	src.server.addLine(src.name, kindKill, "Process killed")
+}
+
+func (src *source) OnStop() {
+	src.flush()
+}
+
+func (src *source) OnExit(status mediator.ExitStatus) {
+	src.flush()
+
+	src.mu.Lock()
+	src.running = false
+	src.exit = &status
+	src.mu.Unlock()
+
+	src.server.addLine(src.name, kindExit, fmt.Sprintf("── %s · %s ──", status, status.Duration.Round(time.Millisecond)))
+	src.server.sendStatus(src.status())
+}
+
+func (src *source) OnStep(step mediator.Step) {
+	src.flush()
+	src.server.addLine(src.name, kindStep, fmt.Sprintf("── step %d/%d · %s ──", step.Index, step.Total, step.Command))
+
+	// the last step is the command itself, started once the previous
+	// run was stopped
+	if step.Index == step.Total {
+		src.mu.Lock()
+		src.running = true
+		src.exit = nil
+		src.mu.Unlock()
+
+		src.server.sendStatus(src.status())
+	}
+}
+
+func (src *source) OnOutput(output string) {
+	src.mu.Lock()
+	lines := strings.Split(src.partial+output, "\n")
+	src.partial = lines[len(lines)-1]
+	src.mu.Unlock()
+
+	for _, line := range lines[:len(lines)-1] {
+		// lines from the pty end with \r\n
+		src.server.addLine(src.name, kindOutput, plain.StripEscapes(strings.TrimSuffix(line, "\r")))
+	}
+}
+
+func (src *source) OnRequestRestart() {
+}
+
+func (src *source) OnRequestStop() {
+}
+
+func (src *source) OnFilesChanged(changes mediator.ChangeSet) {
+}
+
+func (src *source) OnRestartScheduled(restart mediator.ScheduledRestart) {
+	delay := time.Until(restart.At).Round(100 * time.Millisecond)
+	src.server.addLine(src.name, kindRestart, fmt.Sprintf("Restarting in %s (attempt %d)", delay, restart.Attempt))
+}
+
+func (src *source) OnCancelRestart() {
+}